@@ -0,0 +1,133 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime/debug"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/zeromicro/go-zero/core/threading"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+)
+
+// Server 与 Kitex 服务一同启停的内部 HTTP 服务，只应暴露在集群内部
+type Server struct {
+	config    *config.Config
+	mongo     *mongo.Database
+	redis     *redis.Redis
+	server    *http.Server
+	startTime time.Time
+}
+
+func NewServer(config *config.Config, lc *lifecycle.Lifecycle, mongo *mongo.Database, redis *redis.Redis) *Server {
+	s := &Server{
+		config:    config,
+		mongo:     mongo,
+		redis:     redis,
+		startTime: time.Now(),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+	mux.HandleFunc("/buildinfo", s.buildInfo)
+	mux.HandleFunc("/config", s.currentConfig)
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.server = &http.Server{
+		Addr:    config.Admin.ListenOn,
+		Handler: mux,
+	}
+	lc.Append(lifecycle.Hook{
+		Name:    "admin",
+		OnStart: s.Start,
+		OnStop:  s.Stop,
+	})
+	return s
+}
+
+// Start 先同步监听端口，端口被占用时直接返回错误
+func (s *Server) Start(context.Context) error {
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		return err
+	}
+	threading.GoSafe(func() {
+		log.Info("[Admin] listening on %s", s.server.Addr)
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("[Admin] serve failed, err=%v", err)
+		}
+	})
+	return nil
+}
+
+func (s *Server) Stop(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// healthz 进程能响应即视为存活，不检查依赖，避免依赖故障时被反复重启
+func (s *Server) healthz(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readyz Mongo 和 Redis 都可达时才接收流量
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), s.config.Admin.PingTimeout)
+	defer cancel()
+	checks := map[string]string{"mongo": "ok", "redis": "ok"}
+	code := http.StatusOK
+	if err := s.mongo.Client().Ping(ctx, nil); err != nil {
+		checks["mongo"] = err.Error()
+		code = http.StatusServiceUnavailable
+	}
+	if !s.redis.PingCtx(ctx) {
+		checks["redis"] = "ping failed"
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, checks)
+}
+
+func (s *Server) buildInfo(w http.ResponseWriter, _ *http.Request) {
+	res := map[string]any{
+		"service":   s.config.Name,
+		"startTime": s.startTime,
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		res["goVersion"] = info.GoVersion
+		res["module"] = info.Main.Path
+		res["version"] = info.Main.Version
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision", "vcs.time", "vcs.modified":
+				res[setting.Key] = setting.Value
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func (s *Server) currentConfig(w http.ResponseWriter, _ *http.Request) {
+	res, err := redactConfig(s.config)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/cloudwego/kitex/pkg/endpoint"
+	"github.com/cloudwego/kitex/pkg/rpcinfo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/application/service"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+)
+
+// kitexArgs kitex 生成的请求参数都实现了该接口
+type kitexArgs interface {
+	GetFirstArgument() interface{}
+}
+
+// AuditMiddleware 为写接口记录调用者、目标以及调用前后的快照，调用者身份由 AuthMiddleware 放入 ctx
+func AuditMiddleware(auditService service.AuditService) endpoint.Middleware {
+	return func(handler endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, req, resp interface{}) error {
+			ri := rpcinfo.GetRPCInfo(ctx)
+			args, ok := req.(kitexArgs)
+			if ri == nil || !ok || !auditService.Audited(ri.To().Method()) {
+				return handler(ctx, req, resp)
+			}
+
+			arg := args.GetFirstArgument()
+			data := auditService.Begin(ctx, ri.To().Method(), arg)
+			id := identity.FromContext(ctx)
+			data.Caller = id.Caller
+			data.Actor = id.UserId
+			err := handler(ctx, req, resp)
+			auditService.End(ctx, data, arg, err)
+			return err
+		}
+	}
+}
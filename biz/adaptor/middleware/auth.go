@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/bytedance/gopkg/cloud/metainfo"
+	"github.com/cloudwego/kitex/pkg/endpoint"
+	"github.com/cloudwego/kitex/pkg/rpcinfo"
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+)
+
+// userIdGetter 带有 UserId 字段的请求都会生成该方法
+type userIdGetter interface {
+	GetUserId() string
+}
+
+type policy struct {
+	callers []string
+	roles   []string
+}
+
+// AuthMiddleware 从 metainfo 中取出调用方身份放入 ctx，并按配置的策略校验调用方是否有权访问该接口，
+// 带有终端用户的调用只能读写该用户自己的通知。调用方只认签名校验通过的令牌，rpcinfo 中的服务名可以伪造，
+// 只在关闭鉴权时用于日志和审计
+func AuthMiddleware(config *config.Config) endpoint.Middleware {
+	policies := map[string]*policy{}
+	for _, p := range config.Auth.Policies {
+		policies[p.Method] = &policy{callers: p.Callers, roles: p.Roles}
+	}
+	secrets := map[string]string{}
+	for _, c := range config.Auth.Callers {
+		secrets[c.Name] = c.Secret
+	}
+	return func(handler endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, req, resp interface{}) error {
+			ri := rpcinfo.GetRPCInfo(ctx)
+			id := &identity.Identity{}
+			id.UserId, _ = metainfo.GetPersistentValue(ctx, consts.UserIdMetaKey)
+			id.Role, _ = metainfo.GetPersistentValue(ctx, consts.RoleMetaKey)
+			id.AppId, _ = metainfo.GetPersistentValue(ctx, consts.AppIdMetaKey)
+			if !config.Auth.Enabled {
+				if ri != nil {
+					id.Caller = ri.From().ServiceName()
+				}
+				return handler(identity.WithIdentity(ctx, id), req, resp)
+			}
+
+			token, _ := metainfo.GetPersistentValue(ctx, consts.CallerTokenMetaKey)
+			caller, ok := identity.Verify(secrets, token, id, time.Now())
+			if ok {
+				id.Caller = caller
+			} else {
+				// 未通过校验时透传的用户、角色和应用都不可信，只保留空身份，只能访问对任意调用方开放的接口
+				id = &identity.Identity{}
+			}
+			ctx = identity.WithIdentity(ctx, id)
+			if ri == nil {
+				return handler(ctx, req, resp)
+			}
+
+			p, ok := policies[ri.To().Method()]
+			if !ok {
+				p = &policy{callers: config.Auth.DefaultCallers}
+			}
+			if !matchAny(p.callers, id.Caller) && !matchAny(p.roles, id.Role) {
+				return consts.ErrPermissionDenied
+			}
+			if args, ok := req.(kitexArgs); ok {
+				if r, ok := args.GetFirstArgument().(userIdGetter); ok && !canAccessUser(config, id, r.GetUserId()) {
+					return consts.ErrPermissionDenied
+				}
+			}
+			return handler(ctx, req, resp)
+		}
+	}
+}
+
+func matchAny(allowed []string, v string) bool {
+	return lo.Contains(allowed, "*") || (v != "" && lo.Contains(allowed, v))
+}
+
+// canAccessUser 通过校验且没有透传终端用户的服务间调用不受限制，管理员角色可以访问任意用户
+func canAccessUser(config *config.Config, id *identity.Identity, userId string) bool {
+	if id.UserId == "" {
+		return id.Caller != ""
+	}
+	return id.UserId == userId || id.HasRole(config.Auth.AdminRoles)
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/cloudwego/kitex/pkg/endpoint"
+	"github.com/cloudwego/kitex/pkg/rpcinfo"
+	"github.com/zeromicro/go-zero/core/load"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/limiter"
+)
+
+// OverloadMiddleware CPU 过高时自适应丢弃请求，并按配置对接口和终端用户限流，两者都返回 ErrOverloaded，
+// 终端用户由 AuthMiddleware 放入 ctx
+func OverloadMiddleware(config *config.Config, rpcLimiter limiter.IRPCLimiter) endpoint.Middleware {
+	var shedder load.Shedder
+	if config.Overload.CpuThreshold > 0 {
+		shedder = load.NewAdaptiveShedder(load.WithCpuThreshold(config.Overload.CpuThreshold))
+	}
+	stat := load.NewSheddingStat(config.Name)
+	return func(handler endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, req, resp interface{}) (err error) {
+			if shedder != nil {
+				stat.IncrementTotal()
+				promise, shedErr := shedder.Allow()
+				if shedErr != nil {
+					stat.IncrementDrop()
+					return consts.ErrOverloaded
+				}
+				defer func() {
+					// 只有超时说明服务已经处理不过来
+					if errors.Is(err, context.DeadlineExceeded) {
+						promise.Fail()
+					} else {
+						stat.IncrementPass()
+						promise.Pass()
+					}
+				}()
+			}
+
+			if ri := rpcinfo.GetRPCInfo(ctx); ri != nil {
+				method := ri.To().Method()
+				userId := identity.FromContext(ctx).UserId
+				if !rpcLimiter.Allow(ctx, method, userId) {
+					log.CtxInfo(ctx, "[Overload] %s from user %s is rate limited", method, userId)
+					return consts.ErrOverloaded
+				}
+			}
+			return handler(ctx, req, resp)
+		}
+	}
+}
@@ -4,13 +4,27 @@ import (
 	"context"
 	"github.com/CloudStriver/service-idl-gen-go/kitex_gen/cloudmind/system"
 
+	"github.com/CloudStriver/cloudmind-system/biz/adaptor/admin"
 	"github.com/CloudStriver/cloudmind-system/biz/application/service"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/limiter"
 )
 
 type SystemServerImpl struct {
 	*config.Config
-	SystemService service.SystemService
+	SystemService    service.SystemService
+	DigestService    service.DigestService
+	EventService     service.EventService
+	WebhookService   service.WebhookService
+	RetentionService service.RetentionService
+	SnoozeService    service.SnoozeService
+	AnalyticsService service.AnalyticsService
+	AdminService     service.AdminService
+	AuditService     service.AuditService
+	RPCLimiter       limiter.IRPCLimiter
+	AdminServer      *admin.Server
+	Lifecycle        *lifecycle.Lifecycle
 }
 
 func (s *SystemServerImpl) DeleteNotifications(ctx context.Context, req *system.DeleteNotificationsReq) (res *system.DeleteNotificationsResp, err error) {
@@ -21,9 +35,21 @@ func (s *SystemServerImpl) CreateNotificationCount(ctx context.Context, req *sys
 	return s.SystemService.CreateNotificationCount(ctx, req)
 }
 
-//	func (s *SystemServerImpl) UpdateNotifications(ctx context.Context, req *system.UpdateNotificationsReq) (resp *system.UpdateNotificationsResp, err error) {
-//		return s.SystemService.UpdateNotifications(ctx, req)
-//	}
+// 以下 RPC 的服务层已经实现，但当前版本的 service-idl-gen-go 中还没有定义，暂时无法调用。
+// 升级 IDL 后在这里补上 adaptor 方法，并在 Auth.Policies 中配置允许的调用方和角色：
+//   - AckNotifications、GetPendingAckNotifications、GetNotificationAckStats：SystemService 同名方法
+//   - GetDeliveries：DeliveryService.GetDeliveries，需要在 SystemServerImpl 中注入 DeliveryService
+//   - CreateWebhook、UpdateWebhook、DeleteWebhook、GetWebhooks、GetWebhookDeliveries：WebhookService 同名方法
+//   - UpdateNotifications：SystemService.UpdateNotifications，请求转换为 service.UpdateNotificationsReq
+//   - StarNotifications：SystemService 同名方法；GetNotifications 按收藏筛选：GetNotificationsOptions.OnlyStarred
+//   - SnoozeNotification：SystemService 同名方法
+//   - ArchiveNotifications、LabelNotifications、GetUnreadCountByFolder：SystemService 同名方法；GetNotifications 按文件夹筛选：GetNotificationsOptions.OnlyFolder
+//   - SearchNotifications：SystemService 同名方法
+//   - SyncNotifications、MarkNotificationsRead：SystemService 同名方法；GetNotifications 的游标类型与不记已读：GetNotificationsOptions.CursorType、SkipMarkRead
+//   - BlockUser、UnblockUser、GetBlockedUserIds：SystemService 同名方法
+//   - RecordClick、GetNotificationStats：AnalyticsService 同名方法
+//   - AdminSearchNotifications、AdminDeleteNotifications、AdminRetractNotifications：AdminService 的 SearchNotifications、DeleteNotifications、RetractNotifications
+//   - GetAuditLogs：AuditService.GetAuditLogs
 
 func (s *SystemServerImpl) GetSliders(ctx context.Context, req *system.GetSlidersReq) (resp *system.GetSlidersResp, err error) {
 	return s.SystemService.GetSliders(ctx, req)
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"github.com/CloudStriver/go-pkg/utils/pconvertor"
+	"github.com/CloudStriver/service-idl-gen-go/kitex_gen/basic"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	tombstonemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/tombstone"
+)
+
+// AdminService 供客服后台跨用户检索和批量处理通知
+type AdminService interface {
+	SearchNotifications(ctx context.Context, opts *AdminSearchOptions, popts *basic.PaginationOptions) (*AdminSearchResult, error)
+	DeleteNotifications(ctx context.Context, opts *AdminSearchOptions, dryRun bool) (int64, error)
+	RetractNotifications(ctx context.Context, opts *AdminSearchOptions, dryRun bool) (int64, error)
+}
+
+type AdminServiceImpl struct {
+	NotificationMongoMapper notificationmapper.INotificationMongoMapper
+	TombstoneMongoMapper    tombstonemapper.ITombstoneMongoMapper
+	WebhookService          WebhookService
+	AuditService            AuditService
+}
+
+// AdminSearchOptions 检索条件，为 nil 的条件不参与筛选
+type AdminSearchOptions struct {
+	TargetUserId    *string    `json:"targetUserId,omitempty"`
+	SourceUserId    *string    `json:"sourceUserId,omitempty"`
+	SourceContentId *string    `json:"sourceContentId,omitempty"`
+	Type            *int64     `json:"type,omitempty"`
+	CreateAtFrom    *time.Time `json:"createAtFrom,omitempty"`
+	CreateAtTo      *time.Time `json:"createAtTo,omitempty"`
+}
+
+type AdminSearchResult struct {
+	Notifications []*notificationmapper.Notification
+	Total         int64
+	Token         string
+}
+
+// toFilterOptions 管理员可以看到稍后提醒、被屏蔽隐藏和已撤回的通知
+func (o *AdminSearchOptions) toFilterOptions() *notificationmapper.FilterOptions {
+	return &notificationmapper.FilterOptions{
+		OnlyUserId:          o.TargetUserId,
+		OnlySourceUserId:    o.SourceUserId,
+		OnlySourceContentId: o.SourceContentId,
+		OnlyType:            o.Type,
+		OnlyCreateAtAfter:   o.CreateAtFrom,
+		OnlyCreateAtBefore:  o.CreateAtTo,
+		IncludeSnoozed:      true,
+		IncludeHidden:       true,
+		IncludeRetracted:    true,
+	}
+}
+
+func (s *AdminServiceImpl) SearchNotifications(ctx context.Context, opts *AdminSearchOptions, popts *basic.PaginationOptions) (*AdminSearchResult, error) {
+	p := pconvertor.PaginationOptionsToModelPaginationOptions(popts)
+	notifications, total, err := s.NotificationMongoMapper.GetNotificationsAndCount(ctx, opts.toFilterOptions(), p, mongop.IdCursorType)
+	if err != nil {
+		return nil, err
+	}
+	res := &AdminSearchResult{
+		Notifications: notifications,
+		Total:         total,
+	}
+	if p.LastToken != nil {
+		res.Token = *p.LastToken
+	}
+	return res, nil
+}
+
+// DeleteNotifications 删除全部满足条件的通知，dryRun 为 true 时只返回将被删除的数量
+func (s *AdminServiceImpl) DeleteNotifications(ctx context.Context, opts *AdminSearchOptions, dryRun bool) (int64, error) {
+	fopts, err := s.checkBulkOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	if dryRun {
+		return s.NotificationMongoMapper.Count(ctx, fopts)
+	}
+	// 分批处理中途失败时已经处理的批次不会回滚，同样需要审计和推送实际处理的数量
+	n, err := deleteNotifications(ctx, s.NotificationMongoMapper, s.TombstoneMongoMapper, fopts)
+	s.AuditService.Record(ctx, "AdminDeleteNotifications", nil, nil, map[string]any{"filter": opts, "count": n}, err)
+	if n > 0 {
+		s.WebhookService.Publish(ctx, consts.NotificationDeletedHook, map[string]any{
+			"filter": opts,
+			"count":  n,
+		})
+	}
+	return n, err
+}
+
+// RetractNotifications 撤回全部满足条件的通知，撤回后用户不再看到，但记录保留供排查，dryRun 为 true 时只返回将被撤回的数量
+func (s *AdminServiceImpl) RetractNotifications(ctx context.Context, opts *AdminSearchOptions, dryRun bool) (int64, error) {
+	fopts, err := s.checkBulkOptions(opts)
+	if err != nil {
+		return 0, err
+	}
+	fopts.IncludeRetracted = false
+	if dryRun {
+		return s.NotificationMongoMapper.Count(ctx, fopts)
+	}
+	n, err := hideNotifications(ctx, s.NotificationMongoMapper, s.TombstoneMongoMapper, fopts, &notificationmapper.UpdateOptions{
+		IsRetracted: lo.ToPtr(true),
+	})
+	s.AuditService.Record(ctx, "AdminRetractNotifications", nil, nil, map[string]any{"filter": opts, "count": n}, err)
+	if n > 0 {
+		s.WebhookService.Publish(ctx, consts.NotificationRetractedHook, map[string]any{
+			"filter": opts,
+			"count":  n,
+		})
+	}
+	return n, err
+}
+
+// checkBulkOptions 不允许无条件批量处理全部通知
+func (s *AdminServiceImpl) checkBulkOptions(opts *AdminSearchOptions) (*notificationmapper.FilterOptions, error) {
+	fopts := opts.toFilterOptions()
+	if notificationmapper.IsEmptyFilter(fopts) {
+		return nil, consts.ErrInvalidArgument
+	}
+	return fopts, nil
+}
+
+var AdminSet = wire.NewSet(
+	wire.Struct(new(AdminServiceImpl), "*"),
+	wire.Bind(new(AdminService), new(*AdminServiceImpl)),
+)
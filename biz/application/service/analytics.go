@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	notificationcountmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationCount"
+	notificationeventmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationEvent"
+	notificationstatmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationStat"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+type AnalyticsService interface {
+	RecordOpen(ctx context.Context, userId string, notifications []*notificationmapper.Notification)
+	RecordClick(ctx context.Context, userId string, notificationId string) error
+	RunAggregation(ctx context.Context)
+	GetNotificationStats(ctx context.Context, from time.Time, to time.Time, onlyType *int64) ([]*NotificationStatPoint, error)
+}
+
+type AnalyticsServiceImpl struct {
+	Config                       *config.Config
+	NotificationMongoMapper      notificationmapper.INotificationMongoMapper
+	NotificationCountMongoMapper notificationcountmapper.INotificationCountMongoMapper
+	NotificationEventMongoMapper notificationeventmapper.INotificationEventMongoMapper
+	NotificationStatMongoMapper  notificationstatmapper.INotificationStatMongoMapper
+	location                     *time.Location
+	opens                        chan *openBatch
+}
+
+// openBatch 一次列表查询产生的打开记录，ctx 只保留调用方身份，不随请求结束而取消
+type openBatch struct {
+	ctx    context.Context
+	events []*notificationeventmapper.NotificationEvent
+}
+
+// NotificationStatPoint 某一天某种通知的汇总以及打开率、点击率
+type NotificationStatPoint struct {
+	*notificationstatmapper.NotificationStat
+	OpenRate  float64 `json:"openRate"`
+	ClickRate float64 `json:"clickRate"`
+}
+
+type statKey struct {
+	Type       int64
+	TargetType int64
+}
+
+func NewAnalyticsService(config *config.Config, lc *lifecycle.Lifecycle, notificationMongoMapper notificationmapper.INotificationMongoMapper,
+	notificationCountMongoMapper notificationcountmapper.INotificationCountMongoMapper,
+	notificationEventMongoMapper notificationeventmapper.INotificationEventMongoMapper,
+	notificationStatMongoMapper notificationstatmapper.INotificationStatMongoMapper) (AnalyticsService, error) {
+	loc, err := time.LoadLocation(config.Analytics.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+	s := &AnalyticsServiceImpl{
+		Config:                       config,
+		NotificationMongoMapper:      notificationMongoMapper,
+		NotificationCountMongoMapper: notificationCountMongoMapper,
+		NotificationEventMongoMapper: notificationEventMongoMapper,
+		NotificationStatMongoMapper:  notificationStatMongoMapper,
+		location:                     loc,
+		opens:                        make(chan *openBatch, config.Analytics.OpenBuffer),
+	}
+	lc.Append(lifecycle.Ticker(identity.SystemContext(), "analytics", config.Analytics.Interval, s.RunAggregation))
+	lc.Append(lifecycle.Worker("analytics-open", s.writeOpens))
+	return s, nil
+}
+
+// RecordOpen 记录用户在列表中看到了这些通知。记录在后台写入，不阻塞列表的返回，积压过多时直接丢弃
+func (s *AnalyticsServiceImpl) RecordOpen(ctx context.Context, userId string, notifications []*notificationmapper.Notification) {
+	if len(notifications) == 0 {
+		return
+	}
+	batch := &openBatch{
+		ctx: identity.WithIdentity(context.Background(), identity.FromContext(ctx)),
+		events: lo.Map[*notificationmapper.Notification, *notificationeventmapper.NotificationEvent](notifications,
+			func(item *notificationmapper.Notification, _ int) *notificationeventmapper.NotificationEvent {
+				return makeNotificationEvent(userId, item, consts.OpenEvent)
+			}),
+	}
+	select {
+	case s.opens <- batch:
+	default:
+		log.CtxError(ctx, "[Analytics] open buffer is full, drop %d events", len(batch.events))
+	}
+}
+
+// writeOpens 逐批写入打开记录，停止时写完已经缓冲的记录再返回
+func (s *AnalyticsServiceImpl) writeOpens(stop <-chan struct{}) {
+	write := func(batch *openBatch) {
+		if err := s.NotificationEventMongoMapper.Record(batch.ctx, batch.events); err != nil {
+			log.CtxError(batch.ctx, "[Analytics] record open events failed, err=%v", err)
+		}
+	}
+	for {
+		select {
+		case batch := <-s.opens:
+			write(batch)
+		case <-stop:
+			for {
+				select {
+				case batch := <-s.opens:
+					write(batch)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// RecordClick 记录用户点击了通知，点击同时视为打开
+func (s *AnalyticsServiceImpl) RecordClick(ctx context.Context, userId string, notificationId string) error {
+	notifications, err := s.NotificationMongoMapper.FindMany(ctx, &notificationmapper.FilterOptions{
+		OnlyUserIds:         []string{userId, consts.NotificationSystemKey},
+		OnlyNotificationIds: []string{notificationId},
+		IncludeSnoozed:      true,
+	})
+	if err != nil {
+		return err
+	}
+	if len(notifications) == 0 {
+		return consts.ErrNotFound
+	}
+	return s.NotificationEventMongoMapper.Record(ctx, []*notificationeventmapper.NotificationEvent{
+		makeNotificationEvent(userId, notifications[0], consts.OpenEvent),
+		makeNotificationEvent(userId, notifications[0], consts.ClickEvent),
+	})
+}
+
+func makeNotificationEvent(userId string, notification *notificationmapper.Notification, event string) *notificationeventmapper.NotificationEvent {
+	return &notificationeventmapper.NotificationEvent{
+		NotificationId:       notification.ID.Hex(),
+		UserId:               userId,
+		Type:                 notification.Type,
+		TargetType:           notification.TargetType,
+		Event:                event,
+		NotificationCreateAt: notification.CreateAt,
+	}
+}
+
+// RunAggregation 逐个应用重新汇总最近 Analytics.Days 天的数据。互动计入通知创建当天，旧通知被打开时前几天的数据也会变化
+func (s *AnalyticsServiceImpl) RunAggregation(ctx context.Context) {
+	defer metrics.ObserveJob("analytics", time.Now())
+	appIds, err := s.NotificationMongoMapper.GetAppIds(ctx)
+	if err != nil {
+		log.CtxError(ctx, "[Analytics] get apps failed, err=%v", err)
+		return
+	}
+	today := s.startOfDay(time.Now())
+	for _, appId := range appIds {
+		appCtx := identity.AppContext(ctx, appId)
+		for i := s.Config.Analytics.Days - 1; i >= 0; i-- {
+			day := today.AddDate(0, 0, -i)
+			if err = s.aggregateDay(appCtx, day); err != nil {
+				log.CtxError(ctx, "[Analytics] aggregate %s of app %q failed, err=%v", day.Format(consts.StatDateLayout), appId, err)
+			}
+		}
+	}
+}
+
+func (s *AnalyticsServiceImpl) aggregateDay(ctx context.Context, day time.Time) error {
+	next := day.AddDate(0, 0, 1)
+	created, err := s.NotificationMongoMapper.CountCreatedByType(ctx, day, next)
+	if err != nil {
+		return err
+	}
+	events, err := s.NotificationEventMongoMapper.CountByType(ctx, day, next)
+	if err != nil {
+		return err
+	}
+	// 全站广播只存一条通知，但每个用户都能打开，送达人次按接收人数计算，打开率才不会超过 1
+	var recipients int64
+	if lo.ContainsBy(created, func(item *notificationmapper.TypeCount) bool { return item.ID.Broadcast }) {
+		if recipients, err = s.NotificationCountMongoMapper.CountUsers(ctx); err != nil {
+			return err
+		}
+	}
+
+	date := day.Format(consts.StatDateLayout)
+	stats := map[statKey]*notificationstatmapper.NotificationStat{}
+	get := func(key statKey) *notificationstatmapper.NotificationStat {
+		if _, ok := stats[key]; !ok {
+			stats[key] = &notificationstatmapper.NotificationStat{Date: date, Type: key.Type, TargetType: key.TargetType}
+		}
+		return stats[key]
+	}
+	for _, c := range created {
+		stat := get(statKey{Type: c.ID.Type, TargetType: c.ID.TargetType})
+		stat.Created += c.Count
+		if c.ID.Broadcast {
+			stat.Delivered += c.Count * recipients
+		} else {
+			stat.Delivered += c.Count
+		}
+	}
+	for _, c := range events {
+		stat := get(statKey{Type: c.ID.Type, TargetType: c.ID.TargetType})
+		switch c.ID.Event {
+		case consts.OpenEvent:
+			stat.Opened = c.Count
+		case consts.ClickEvent:
+			stat.Clicked = c.Count
+		}
+	}
+	for _, stat := range stats {
+		if err = s.NotificationStatMongoMapper.Upsert(ctx, stat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNotificationStats 按天返回 [from, to] 内各通知类型的创建、送达、打开、点击数，比率以送达人次为分母
+func (s *AnalyticsServiceImpl) GetNotificationStats(ctx context.Context, from time.Time, to time.Time, onlyType *int64) ([]*NotificationStatPoint, error) {
+	if to.Before(from) {
+		return nil, consts.ErrInvalidArgument
+	}
+	stats, err := s.NotificationStatMongoMapper.GetStats(ctx, from.In(s.location).Format(consts.StatDateLayout),
+		to.In(s.location).Format(consts.StatDateLayout), onlyType)
+	if err != nil {
+		return nil, err
+	}
+	return lo.Map[*notificationstatmapper.NotificationStat, *NotificationStatPoint](stats,
+		func(item *notificationstatmapper.NotificationStat, _ int) *NotificationStatPoint {
+			p := &NotificationStatPoint{NotificationStat: item}
+			if item.Delivered > 0 {
+				p.OpenRate = float64(item.Opened) / float64(item.Delivered)
+				p.ClickRate = float64(item.Clicked) / float64(item.Delivered)
+			}
+			return p
+		}), nil
+}
+
+func (s *AnalyticsServiceImpl) startOfDay(t time.Time) time.Time {
+	y, m, d := t.In(s.location).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, s.location)
+}
+
+var AnalyticsSet = wire.NewSet(
+	NewAnalyticsService,
+)
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"github.com/CloudStriver/go-pkg/utils/pconvertor"
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/CloudStriver/service-idl-gen-go/kitex_gen/basic"
+	gensystem "github.com/CloudStriver/service-idl-gen-go/kitex_gen/cloudmind/system"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	auditmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/audit"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	slidermapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/slider"
+)
+
+// AuditService 记录写接口的调用者、目标以及调用前后的快照
+type AuditService interface {
+	Audited(method string) bool
+	Begin(ctx context.Context, method string, req any) *auditmapper.AuditLog
+	End(ctx context.Context, data *auditmapper.AuditLog, req any, err error)
+	Record(ctx context.Context, method string, targetIds []string, before any, after any, err error)
+	GetAuditLogs(ctx context.Context, fopts *auditmapper.FilterOptions, popts *basic.PaginationOptions) (*AuditLogsResult, error)
+}
+
+type AuditServiceImpl struct {
+	AuditMongoMapper        auditmapper.IAuditMongoMapper
+	SliderMongoMapper       slidermapper.ISliderMongoMapper
+	NotificationMongoMapper notificationmapper.INotificationMongoMapper
+}
+
+type AuditLogsResult struct {
+	Logs  []*auditmapper.AuditLog
+	Total int64
+	Token string
+}
+
+// auditor 描述一个写接口的审计方式，create 为 true 的接口调用前没有快照，调用后的快照就是请求本身
+type auditor struct {
+	create    bool
+	targetIds func(req any) []string
+	snapshot  func(ctx context.Context, s *AuditServiceImpl, req any) (any, error)
+}
+
+var auditors = map[string]*auditor{
+	"CreateSlider": {
+		create: true,
+	},
+	"UpdateSlider": {
+		targetIds: func(req any) []string { return []string{req.(*gensystem.UpdateSliderReq).SliderId} },
+		snapshot: func(ctx context.Context, s *AuditServiceImpl, req any) (any, error) {
+			return s.SliderMongoMapper.FindOne(ctx, req.(*gensystem.UpdateSliderReq).SliderId)
+		},
+	},
+	"DeleteSlider": {
+		targetIds: func(req any) []string { return []string{req.(*gensystem.DeleteSliderReq).SliderId} },
+		snapshot: func(ctx context.Context, s *AuditServiceImpl, req any) (any, error) {
+			return s.SliderMongoMapper.FindOne(ctx, req.(*gensystem.DeleteSliderReq).SliderId)
+		},
+	},
+	"CreateNotifications": {
+		create:    true,
+		targetIds: func(req any) []string { return []string{req.(*gensystem.CreateNotificationsReq).TargetUserId} },
+	},
+	"CreateNotificationCount": {
+		create:    true,
+		targetIds: func(req any) []string { return []string{req.(*gensystem.CreateNotificationCountReq).UserId} },
+	},
+	"DeleteNotifications": {
+		targetIds: func(req any) []string {
+			r := req.(*gensystem.DeleteNotificationsReq)
+			return append([]string{r.UserId}, r.NotificationIds...)
+		},
+		snapshot: func(ctx context.Context, s *AuditServiceImpl, req any) (any, error) {
+			r := req.(*gensystem.DeleteNotificationsReq)
+			return s.NotificationMongoMapper.FindMany(ctx, &notificationmapper.FilterOptions{
+				OnlyUserId:          lo.ToPtr(r.UserId),
+				OnlyNotificationIds: r.NotificationIds,
+				OnlyType:            r.OnlyType,
+				IncludeSnoozed:      true,
+			})
+		},
+	},
+}
+
+func (s *AuditServiceImpl) Audited(method string) bool {
+	_, ok := auditors[method]
+	return ok
+}
+
+// Begin 在调用前生成审计记录并保存调用前的快照
+func (s *AuditServiceImpl) Begin(ctx context.Context, method string, req any) *auditmapper.AuditLog {
+	a := auditors[method]
+	data := &auditmapper.AuditLog{Method: method}
+	if a.targetIds != nil {
+		data.TargetIds = a.targetIds(req)
+	}
+	if !a.create {
+		data.Before = s.snapshot(ctx, a, req)
+	}
+	return data
+}
+
+// End 保存调用后的快照和错误并写入审计日志，写入失败不影响调用结果
+func (s *AuditServiceImpl) End(ctx context.Context, data *auditmapper.AuditLog, req any, err error) {
+	a := auditors[data.Method]
+	switch {
+	case err != nil:
+		data.Error = err.Error()
+	case a.create:
+		data.After = marshalSnapshot(req)
+	default:
+		data.After = s.snapshot(ctx, a, req)
+	}
+	if err = s.AuditMongoMapper.InsertOne(ctx, data); err != nil {
+		log.CtxError(ctx, "[Audit] save audit log of %s failed, err=%v", data.Method, err)
+	}
+}
+
+// Record 记录没有对应 RPC、不经过审计中间件的写操作，before、after 为操作前后的快照，为 nil 时不记录
+func (s *AuditServiceImpl) Record(ctx context.Context, method string, targetIds []string, before any, after any, err error) {
+	id := identity.FromContext(ctx)
+	data := &auditmapper.AuditLog{
+		Caller:    id.Caller,
+		Actor:     id.UserId,
+		Method:    method,
+		TargetIds: targetIds,
+	}
+	if before != nil {
+		data.Before = marshalSnapshot(before)
+	}
+	switch {
+	case err != nil:
+		data.Error = err.Error()
+	case after != nil:
+		data.After = marshalSnapshot(after)
+	}
+	if err = s.AuditMongoMapper.InsertOne(ctx, data); err != nil {
+		log.CtxError(ctx, "[Audit] save audit log of %s failed, err=%v", method, err)
+	}
+}
+
+func (s *AuditServiceImpl) snapshot(ctx context.Context, a *auditor, req any) string {
+	if a.snapshot == nil {
+		return ""
+	}
+	v, err := a.snapshot(ctx, s, req)
+	switch {
+	case errors.Is(err, consts.ErrNotFound):
+		return ""
+	case err != nil:
+		log.CtxError(ctx, "[Audit] take snapshot failed, err=%v", err)
+		return ""
+	}
+	return marshalSnapshot(v)
+}
+
+func marshalSnapshot(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func (s *AuditServiceImpl) GetAuditLogs(ctx context.Context, fopts *auditmapper.FilterOptions, popts *basic.PaginationOptions) (*AuditLogsResult, error) {
+	p := pconvertor.PaginationOptionsToModelPaginationOptions(popts)
+	logs, total, err := s.AuditMongoMapper.GetAuditLogsAndCount(ctx, fopts, p, mongop.IdCursorType)
+	if err != nil {
+		return nil, err
+	}
+	res := &AuditLogsResult{
+		Logs:  logs,
+		Total: total,
+	}
+	if p.LastToken != nil {
+		res.Token = *p.LastToken
+	}
+	return res, nil
+}
+
+var AuditSet = wire.NewSet(
+	wire.Struct(new(AuditServiceImpl), "*"),
+	wire.Bind(new(AuditService), new(*AuditServiceImpl)),
+)
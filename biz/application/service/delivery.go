@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/channel"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	deliverymapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/delivery"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+type DeliveryService interface {
+	Dispatch(ctx context.Context, notification *notificationmapper.Notification) error
+	GetDeliveries(ctx context.Context, notificationId string) ([]*deliverymapper.Delivery, error)
+	RunDueDeliveries(ctx context.Context)
+}
+
+type DeliveryServiceImpl struct {
+	Config              *config.Config
+	DeliveryMongoMapper deliverymapper.IDeliveryMongoMapper
+	Channels            channel.Channels
+	// PreferenceMongoMapper 用于读取用户的时区与免打扰时段
+	PreferenceMongoMapper preferencemapper.IPreferenceMongoMapper
+}
+
+func NewDeliveryService(config *config.Config, lc *lifecycle.Lifecycle, deliveryMongoMapper deliverymapper.IDeliveryMongoMapper, channels channel.Channels,
+	preferenceMongoMapper preferencemapper.IPreferenceMongoMapper) DeliveryService {
+	s := &DeliveryServiceImpl{
+		Config:                config,
+		DeliveryMongoMapper:   deliveryMongoMapper,
+		Channels:              channels,
+		PreferenceMongoMapper: preferenceMongoMapper,
+	}
+	lc.Append(lifecycle.Ticker(identity.SystemContext(), "delivery", config.Delivery.Interval, s.RunDueDeliveries))
+	return s
+}
+
+// Dispatch 按通知类型的路由配置生成站外投递记录，实际发送由后台任务完成
+func (s *DeliveryServiceImpl) Dispatch(ctx context.Context, notification *notificationmapper.Notification) error {
+	// 全站广播只进入站内信箱
+	if notification.TargetUserId == consts.NotificationSystemKey {
+		return nil
+	}
+	var channels []string
+	for _, route := range s.Config.RoutesOf(notification.AppId) {
+		if route.Type == notification.Type {
+			channels = append(channels, route.Channels...)
+		}
+	}
+	if len(channels) == 0 {
+		return nil
+	}
+	// 免打扰时段内的站外投递延后到时段结束，站内信箱不受影响
+	nextAttemptAt := time.Now()
+	p, err := s.PreferenceMongoMapper.GetPreference(ctx, notification.TargetUserId)
+	switch {
+	case err == nil:
+		if end, ok := quietHoursEnd(p, nextAttemptAt); ok {
+			nextAttemptAt = end
+		}
+	case !errors.Is(err, consts.ErrNotFound) && !errors.Is(err, consts.ErrInvalidObjectId):
+		return err
+	}
+	deliveries := lo.FilterMap[string, *deliverymapper.Delivery](lo.Uniq(channels), func(name string, _ int) (*deliverymapper.Delivery, bool) {
+		if _, ok := s.Channels[name]; !ok {
+			log.CtxError(ctx, "[Delivery] unknown channel %s for type %d", name, notification.Type)
+			return nil, false
+		}
+		return &deliverymapper.Delivery{
+			NotificationId: notification.ID.Hex(),
+			TargetUserId:   notification.TargetUserId,
+			Channel:        name,
+			Type:           notification.Type,
+			Text:           notification.Text,
+			Status:         consts.DeliveryStatusPending,
+			NextAttemptAt:  nextAttemptAt,
+		}, true
+	})
+	return s.DeliveryMongoMapper.InsertMany(ctx, deliveries)
+}
+
+func (s *DeliveryServiceImpl) GetDeliveries(ctx context.Context, notificationId string) ([]*deliverymapper.Delivery, error) {
+	return s.DeliveryMongoMapper.GetDeliveries(ctx, notificationId)
+}
+
+// RunDueDeliveries 依次领取并发送所有应用到期的投递记录，每条记录在其所属应用下发送，失败时按指数退避重试
+func (s *DeliveryServiceImpl) RunDueDeliveries(ctx context.Context) {
+	defer metrics.ObserveJob("delivery", time.Now())
+	// 停止时 ctx 被取消，不再领取新的投递，未完成的投递在租约到期后由其他实例重新领取
+	for ctx.Err() == nil {
+		d, err := s.DeliveryMongoMapper.ClaimDue(ctx, time.Now(), s.Config.Delivery.Lease)
+		if errors.Is(err, consts.ErrNotFound) {
+			return
+		}
+		if err != nil {
+			log.CtxError(ctx, "[Delivery] claim due delivery failed, err=%v", err)
+			return
+		}
+		s.attempt(identity.AppContext(ctx, d.AppId), d)
+	}
+}
+
+func (s *DeliveryServiceImpl) attempt(ctx context.Context, d *deliverymapper.Delivery) {
+	d.Attempts++
+	err := s.Channels[d.Channel].Send(ctx, &channel.Message{
+		NotificationId: d.NotificationId,
+		TargetUserId:   d.TargetUserId,
+		Type:           d.Type,
+		Text:           d.Text,
+	})
+	switch {
+	case err == nil:
+		d.Status = consts.DeliveryStatusSuccess
+	case errors.Is(err, channel.ErrNoAddress) || d.Attempts >= s.Config.Delivery.MaxAttempts:
+		d.Status = consts.DeliveryStatusFailed
+		d.LastError = err.Error()
+	default:
+		d.LastError = err.Error()
+		d.NextAttemptAt = time.Now().Add(exponentialBackoff(s.Config.Delivery.Backoff, s.Config.Delivery.MaxBackoff, d.Attempts))
+	}
+	if err = s.DeliveryMongoMapper.UpdateOne(ctx, d); err != nil {
+		log.CtxError(ctx, "[Delivery] update delivery %s failed, err=%v", d.ID.Hex(), err)
+	}
+}
+
+// exponentialBackoff 第 attempts 次失败后的重试间隔，从 base 开始每次翻倍，不超过 max
+func exponentialBackoff(base, max time.Duration, attempts int64) time.Duration {
+	b := base
+	for i := int64(1); i < attempts && b < max; i++ {
+		b *= 2
+	}
+	if b > max {
+		b = max
+	}
+	return b
+}
+
+// quietHoursEnd 判断 now 是否处于用户的免打扰时段，是则返回时段结束的时间
+func quietHoursEnd(p *preferencemapper.Preference, now time.Time) (time.Time, bool) {
+	if p.QuietStart == "" || p.QuietEnd == "" {
+		return time.Time{}, false
+	}
+	loc, err := time.LoadLocation(p.TimeZone)
+	if err != nil {
+		return time.Time{}, false
+	}
+	start, err1 := time.Parse(consts.QuietHoursLayout, p.QuietStart)
+	end, err2 := time.Parse(consts.QuietHoursLayout, p.QuietEnd)
+	if err1 != nil || err2 != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	y, m, d := local.Date()
+	startAt := time.Date(y, m, d, start.Hour(), start.Minute(), 0, 0, loc)
+	endAt := time.Date(y, m, d, end.Hour(), end.Minute(), 0, 0, loc)
+	switch {
+	case startAt.Equal(endAt):
+		return time.Time{}, false
+	case startAt.Before(endAt):
+		// 同一天内的时段，例如 12:00-14:00
+		return endAt, !local.Before(startAt) && local.Before(endAt)
+	case !local.Before(startAt):
+		// 跨天的时段，例如 22:00-08:00，当前处于前半夜
+		return endAt.AddDate(0, 0, 1), true
+	default:
+		return endAt, local.Before(endAt)
+	}
+}
+
+var DeliverySet = wire.NewSet(
+	NewDeliveryService,
+)
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
+)
+
+func TestQuietHoursEnd(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, shanghai)
+	}
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		timeZone string
+		now      time.Time
+		want     time.Time
+		wantOk   bool
+	}{
+		{name: "not configured", now: at(1, 23, 0)},
+		{name: "only start", start: "22:00", timeZone: "Asia/Shanghai", now: at(1, 23, 0)},
+		{name: "empty range", start: "22:00", end: "22:00", timeZone: "Asia/Shanghai", now: at(1, 22, 0)},
+		{name: "invalid time zone", start: "22:00", end: "08:00", timeZone: "Mars/Base", now: at(1, 23, 0)},
+		{name: "same day inside", start: "12:00", end: "14:00", timeZone: "Asia/Shanghai", now: at(1, 13, 0), want: at(1, 14, 0), wantOk: true},
+		{name: "same day at start", start: "12:00", end: "14:00", timeZone: "Asia/Shanghai", now: at(1, 12, 0), want: at(1, 14, 0), wantOk: true},
+		{name: "same day at end", start: "12:00", end: "14:00", timeZone: "Asia/Shanghai", now: at(1, 14, 0)},
+		{name: "same day before", start: "12:00", end: "14:00", timeZone: "Asia/Shanghai", now: at(1, 11, 59)},
+		{name: "overnight before midnight", start: "22:00", end: "08:00", timeZone: "Asia/Shanghai", now: at(1, 23, 30), want: at(2, 8, 0), wantOk: true},
+		{name: "overnight after midnight", start: "22:00", end: "08:00", timeZone: "Asia/Shanghai", now: at(2, 3, 0), want: at(2, 8, 0), wantOk: true},
+		{name: "overnight daytime", start: "22:00", end: "08:00", timeZone: "Asia/Shanghai", now: at(2, 12, 0)},
+		{name: "overnight across month end", start: "22:00", end: "08:00", timeZone: "Asia/Shanghai", now: at(31, 22, 0), want: time.Date(2024, 4, 1, 8, 0, 0, 0, shanghai), wantOk: true},
+		{name: "now in another zone", start: "22:00", end: "08:00", timeZone: "Asia/Shanghai", now: at(1, 23, 0).UTC(), want: at(2, 8, 0), wantOk: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &preferencemapper.Preference{QuietStart: tt.start, QuietEnd: tt.end, TimeZone: tt.timeZone}
+			got, ok := quietHoursEnd(p, tt.now)
+			if ok != tt.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOk)
+			}
+			if ok && !got.Equal(tt.want) {
+				t.Errorf("end = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int64
+		want     time.Duration
+	}{
+		{attempts: 1, want: time.Second},
+		{attempts: 2, want: 2 * time.Second},
+		{attempts: 4, want: 8 * time.Second},
+		{attempts: 10, want: 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := exponentialBackoff(time.Second, 10*time.Second, tt.attempts); got != tt.want {
+			t.Errorf("exponentialBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination"
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mail"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	notificationcountmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationCount"
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+//go:embed template
+var templateFS embed.FS
+
+var (
+	digestHtmlTemplate = htmltemplate.Must(htmltemplate.ParseFS(templateFS, "template/digest.html.tmpl"))
+	digestTextTemplate = texttemplate.Must(texttemplate.ParseFS(templateFS, "template/digest.txt.tmpl"))
+)
+
+type DigestService interface {
+	RunDigests(ctx context.Context)
+}
+
+type DigestServiceImpl struct {
+	Config                       *config.Config
+	NotificationMongoMapper      notificationmapper.INotificationMongoMapper
+	NotificationCountMongoMapper notificationcountmapper.INotificationCountMongoMapper
+	PreferenceMongoMapper        preferencemapper.IPreferenceMongoMapper
+	MailSender                   mail.Sender
+}
+
+type (
+	digestData struct {
+		Total  int
+		Groups []*digestGroup
+	}
+	digestGroup struct {
+		Type          int64
+		Name          string
+		Notifications []*notificationmapper.Notification
+	}
+)
+
+func NewDigestService(config *config.Config, lc *lifecycle.Lifecycle, notificationMongoMapper notificationmapper.INotificationMongoMapper,
+	notificationCountMongoMapper notificationcountmapper.INotificationCountMongoMapper,
+	preferenceMongoMapper preferencemapper.IPreferenceMongoMapper, mailSender mail.Sender) DigestService {
+	s := &DigestServiceImpl{
+		Config:                       config,
+		NotificationMongoMapper:      notificationMongoMapper,
+		NotificationCountMongoMapper: notificationCountMongoMapper,
+		PreferenceMongoMapper:        preferenceMongoMapper,
+		MailSender:                   mailSender,
+	}
+	lc.Append(lifecycle.Ticker(identity.SystemContext(), "digest", config.Digest.Interval, s.RunDigests))
+	return s
+}
+
+// RunDigests 给所有应用中到期的日报、周报订阅用户发送未读通知摘要，每个用户只汇总其偏好设置所属应用的通知
+func (s *DigestServiceImpl) RunDigests(ctx context.Context) {
+	defer metrics.ObserveJob("digest", time.Now())
+	now := time.Now()
+	for frequency, period := range map[int64]time.Duration{
+		consts.DigestFrequencyDaily:  24 * time.Hour,
+		consts.DigestFrequencyWeekly: 7 * 24 * time.Hour,
+	} {
+		preferences, err := s.PreferenceMongoMapper.GetDigestDuePreferences(ctx, frequency, now.Add(-period))
+		if err != nil {
+			log.CtxError(ctx, "[Digest] get due preferences failed, err=%v", err)
+			continue
+		}
+		for _, p := range preferences {
+			if err = s.sendDigest(identity.AppContext(ctx, p.AppId), p, now); err != nil {
+				log.CtxError(ctx, "[Digest] send digest to %s failed, err=%v", p.GetUserId(), err)
+			}
+		}
+	}
+}
+
+func (s *DigestServiceImpl) sendDigest(ctx context.Context, p *preferencemapper.Preference, now time.Time) error {
+	userId := p.GetUserId()
+	notifications, err := s.getUnreadNotifications(ctx, userId, p.LastDigestAt)
+	if err != nil || len(notifications) == 0 {
+		return err
+	}
+	ok, err := s.PreferenceMongoMapper.ClaimDigest(ctx, p, now, s.Config.Digest.Lease)
+	if err != nil || !ok {
+		return err
+	}
+	if err = s.send(ctx, p, notifications); err != nil {
+		if releaseErr := s.PreferenceMongoMapper.ReleaseDigest(ctx, p); releaseErr != nil {
+			log.CtxError(ctx, "[Digest] release digest of %s failed, err=%v", userId, releaseErr)
+		}
+		return err
+	}
+	return s.PreferenceMongoMapper.CommitDigest(ctx, p, now)
+}
+
+func (s *DigestServiceImpl) send(ctx context.Context, p *preferencemapper.Preference, notifications []*notificationmapper.Notification) error {
+	data := s.groupByType(notifications)
+	var html, text bytes.Buffer
+	if err := digestHtmlTemplate.Execute(&html, data); err != nil {
+		return err
+	}
+	if err := digestTextTemplate.Execute(&text, data); err != nil {
+		return err
+	}
+	return s.MailSender.Send(ctx, &mail.Mail{
+		To:      p.Email,
+		Subject: s.Config.Digest.Subject,
+		Text:    text.String(),
+		Html:    html.String(),
+	})
+}
+
+// getUnreadNotifications 获取用户上次摘要之后的未读通知，最多 Digest.MaxItems 条
+func (s *DigestServiceImpl) getUnreadNotifications(ctx context.Context, userId string, since time.Time) ([]*notificationmapper.Notification, error) {
+	readAt, err := getReadAt(ctx, s.NotificationMongoMapper, s.NotificationCountMongoMapper, userId)
+	if err != nil {
+		return nil, err
+	}
+	if readAt.After(since) {
+		since = readAt
+	}
+	return s.NotificationMongoMapper.GetNotifications(ctx, unreadFilter(userId, since), &pagination.PaginationOptions{
+		Limit: lo.ToPtr(s.Config.Digest.MaxItems),
+	}, notificationmapper.SortAtCursorType)
+}
+
+func (s *DigestServiceImpl) groupByType(notifications []*notificationmapper.Notification) *digestData {
+	groups := lo.GroupBy[*notificationmapper.Notification, int64](notifications, func(item *notificationmapper.Notification) int64 {
+		return item.Type
+	})
+	data := &digestData{Total: len(notifications)}
+	for t, items := range groups {
+		name := fmt.Sprintf("通知类型 %d", t)
+		for _, n := range s.Config.Digest.TypeNames {
+			if n.Type == t {
+				name = n.Name
+			}
+		}
+		data.Groups = append(data.Groups, &digestGroup{
+			Type:          t,
+			Name:          name,
+			Notifications: items,
+		})
+	}
+	sort.Slice(data.Groups, func(i, j int) bool {
+		return data.Groups[i].Type < data.Groups[j].Type
+	})
+	return data
+}
+
+var DigestSet = wire.NewSet(
+	NewDigestService,
+)
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination"
+	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mail"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	notificationcountmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationCount"
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
+)
+
+type digestNotificationMapper struct {
+	notificationmapper.INotificationMongoMapper
+	notifications []*notificationmapper.Notification
+}
+
+func (m *digestNotificationMapper) GetNotifications(context.Context, *notificationmapper.FilterOptions, *pagination.PaginationOptions, mongop.MongoCursor) ([]*notificationmapper.Notification, error) {
+	return m.notifications, nil
+}
+
+type digestCountMapper struct {
+	notificationcountmapper.INotificationCountMongoMapper
+}
+
+func (m *digestCountMapper) GetReadState(context.Context, string) (*notificationcountmapper.NotificationCount, error) {
+	return &notificationcountmapper.NotificationCount{}, nil
+}
+
+type digestPreferenceMapper struct {
+	preferencemapper.IPreferenceMongoMapper
+	claimOk  bool
+	claimErr error
+	claimed  bool
+	commits  int
+	releases int
+}
+
+func (m *digestPreferenceMapper) ClaimDigest(context.Context, *preferencemapper.Preference, time.Time, time.Duration) (bool, error) {
+	m.claimed = true
+	return m.claimOk, m.claimErr
+}
+
+func (m *digestPreferenceMapper) CommitDigest(context.Context, *preferencemapper.Preference, time.Time) error {
+	m.commits++
+	return nil
+}
+
+func (m *digestPreferenceMapper) ReleaseDigest(context.Context, *preferencemapper.Preference) error {
+	m.releases++
+	return nil
+}
+
+type digestSender struct {
+	err   error
+	sends int
+}
+
+func (s *digestSender) Send(context.Context, *mail.Mail) error {
+	s.sends++
+	return s.err
+}
+
+func TestSendDigest(t *testing.T) {
+	sendErr := errors.New("smtp unavailable")
+	claimErr := errors.New("mongo unavailable")
+	tests := []struct {
+		name          string
+		notifications int
+		claimOk       bool
+		claimErr      error
+		sendErr       error
+		wantErr       error
+		wantClaimed   bool
+		wantSends     int
+		wantCommits   int
+		wantReleases  int
+	}{
+		{name: "no unread", notifications: 0, claimOk: true},
+		{name: "claimed by another instance", notifications: 2, wantClaimed: true},
+		{name: "claim error", notifications: 2, claimErr: claimErr, wantErr: claimErr, wantClaimed: true},
+		{name: "send failed", notifications: 2, claimOk: true, sendErr: sendErr, wantErr: sendErr, wantClaimed: true, wantSends: 1, wantReleases: 1},
+		{name: "sent", notifications: 2, claimOk: true, wantClaimed: true, wantSends: 1, wantCommits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &config.Config{}
+			c.Digest.MaxItems = 50
+			c.Digest.Lease = time.Minute
+			notifications := make([]*notificationmapper.Notification, tt.notifications)
+			for i := range notifications {
+				notifications[i] = &notificationmapper.Notification{ID: primitive.NewObjectID(), Type: 1, Text: "hello"}
+			}
+			preferences := &digestPreferenceMapper{claimOk: tt.claimOk, claimErr: tt.claimErr}
+			sender := &digestSender{err: tt.sendErr}
+			s := &DigestServiceImpl{
+				Config:                       c,
+				NotificationMongoMapper:      &digestNotificationMapper{notifications: notifications},
+				NotificationCountMongoMapper: &digestCountMapper{},
+				PreferenceMongoMapper:        preferences,
+				MailSender:                   sender,
+			}
+			p := &preferencemapper.Preference{ID: primitive.NewObjectID(), Email: "user@example.com"}
+			err := s.sendDigest(context.Background(), p, time.Now())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if preferences.claimed != tt.wantClaimed {
+				t.Errorf("claimed = %v, want %v", preferences.claimed, tt.wantClaimed)
+			}
+			if sender.sends != tt.wantSends {
+				t.Errorf("sends = %d, want %d", sender.sends, tt.wantSends)
+			}
+			if preferences.commits != tt.wantCommits {
+				t.Errorf("commits = %d, want %d", preferences.commits, tt.wantCommits)
+			}
+			if preferences.releases != tt.wantReleases {
+				t.Errorf("releases = %d, want %d", preferences.releases, tt.wantReleases)
+			}
+		})
+	}
+}
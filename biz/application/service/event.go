@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"text/template"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/google/wire"
+	"github.com/zeromicro/go-zero/core/threading"
+
+	gensystem "github.com/CloudStriver/service-idl-gen-go/kitex_gen/cloudmind/system"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mq"
+)
+
+type EventService interface {
+	HandleEvent(ctx context.Context, event *mq.Event) error
+}
+
+type EventServiceImpl struct {
+	Config        *config.Config
+	SystemService SystemService
+	AuditService  AuditService
+	Consumer      mq.Consumer
+	rules         []*eventRule
+}
+
+type eventRule struct {
+	event      string
+	typ        int64
+	targetType int64
+	text       *template.Template
+	broadcast  bool
+}
+
+func NewEventService(config *config.Config, lc *lifecycle.Lifecycle, systemService SystemService, auditService AuditService, consumer mq.Consumer) (EventService, error) {
+	s := &EventServiceImpl{
+		Config:        config,
+		SystemService: systemService,
+		AuditService:  auditService,
+		Consumer:      consumer,
+	}
+	for _, r := range config.EventQueue.Rules {
+		text, err := template.New(r.Event).Option("missingkey=zero").Parse(r.Text)
+		if err != nil {
+			return nil, err
+		}
+		s.rules = append(s.rules, &eventRule{
+			event:      r.Event,
+			typ:        r.Type,
+			targetType: r.TargetType,
+			text:       text,
+			broadcast:  r.Broadcast,
+		})
+	}
+	// Stop 后 Start 会在当前事件处理完后返回
+	lc.Append(lifecycle.Worker("event", func(stop <-chan struct{}) {
+		threading.GoSafe(func() {
+			<-stop
+			consumer.Stop()
+		})
+		consumer.Start(s.HandleEvent)
+	}))
+	return s, nil
+}
+
+// eventCaller 审计日志中事件驱动写入的调用方
+const eventCaller = "event"
+
+// HandleEvent 按配置的规则把领域事件转换为通知，复用 CreateNotifications 的全部校验，通知创建在事件所属的应用下，
+// 与 RPC 调用一样记录审计日志
+func (s *EventServiceImpl) HandleEvent(ctx context.Context, event *mq.Event) error {
+	ctx = identity.WithIdentity(ctx, &identity.Identity{Caller: eventCaller, AppId: event.AppId})
+	for _, r := range s.rules {
+		if r.event != event.Type {
+			continue
+		}
+		// 模板渲染失败以及通知校验不通过时重试也不会成功，只记录日志，避免阻塞后续事件的消费
+		var text bytes.Buffer
+		if err := r.text.Execute(&text, event); err != nil {
+			log.CtxError(ctx, "[Event] render text of event %s failed, err=%v", event.Type, err)
+			continue
+		}
+		targetUserId := event.TargetUserId
+		if r.broadcast {
+			targetUserId = consts.NotificationSystemKey
+		}
+		// 自己给自己的内容点赞、评论不产生通知
+		if targetUserId == "" || targetUserId == event.SourceUserId {
+			continue
+		}
+		req := &gensystem.CreateNotificationsReq{
+			TargetUserId:    targetUserId,
+			SourceUserId:    event.SourceUserId,
+			SourceContentId: event.SourceContentId,
+			TargetType:      r.targetType,
+			Type:            r.typ,
+			Text:            text.String(),
+		}
+		_, err := s.SystemService.CreateNotifications(ctx, req)
+		s.AuditService.Record(ctx, "CreateNotifications", []string{targetUserId}, nil,
+			map[string]any{"event": event.Type, "request": req}, err)
+		if errors.Is(err, consts.ErrSensitiveText) || errors.Is(err, consts.ErrInvalidArgument) {
+			log.CtxError(ctx, "[Event] drop notification of event %s, err=%v", event.Type, err)
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var EventSet = wire.NewSet(
+	NewEventService,
+)
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	tombstonemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/tombstone"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const gramBackfillBatchSize = 500
+
+type RetentionService interface {
+	RunRetention(ctx context.Context)
+}
+
+type RetentionServiceImpl struct {
+	Config                  *config.Config
+	NotificationMongoMapper notificationmapper.INotificationMongoMapper
+	TombstoneMongoMapper    tombstonemapper.ITombstoneMongoMapper
+}
+
+func NewRetentionService(config *config.Config, lc *lifecycle.Lifecycle, notificationMongoMapper notificationmapper.INotificationMongoMapper,
+	tombstoneMongoMapper tombstonemapper.ITombstoneMongoMapper) RetentionService {
+	s := &RetentionServiceImpl{
+		Config:                  config,
+		NotificationMongoMapper: notificationMongoMapper,
+		TombstoneMongoMapper:    tombstoneMongoMapper,
+	}
+	lc.Append(lifecycle.Ticker(identity.SystemContext(), "retention", config.Notification.RetentionInterval, s.RunRetention))
+	lc.Append(lifecycle.Worker("grams", func(stop <-chan struct{}) {
+		s.backfillGrams(identity.SystemContext(), stop)
+	}))
+	return s
+}
+
+// backfillGrams 启动后为旧版本写入的没有分词的通知补充分词，补齐之前这些通知无法被检索到
+func (s *RetentionServiceImpl) backfillGrams(ctx context.Context, stop <-chan struct{}) {
+	defer metrics.ObserveJob("grams", time.Now())
+	var total int64
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		n, err := s.NotificationMongoMapper.BackfillGrams(ctx, gramBackfillBatchSize)
+		if err != nil {
+			log.CtxError(ctx, "[Retention] backfill grams failed, err=%v", err)
+			return
+		}
+		if total += n; n == 0 {
+			if total > 0 {
+				log.CtxInfo(ctx, "[Retention] backfilled grams of %d notifications", total)
+			}
+			return
+		}
+	}
+}
+
+// RunRetention 清理超过保留时长且未收藏的通知，以及超过保留时长的删除记录
+func (s *RetentionServiceImpl) RunRetention(ctx context.Context) {
+	defer metrics.ObserveJob("retention", time.Now())
+	now := time.Now()
+	if s.Config.Notification.Retention > 0 {
+		n, err := deleteNotifications(ctx, s.NotificationMongoMapper, s.TombstoneMongoMapper, &notificationmapper.FilterOptions{
+			OnlyCreateAtBefore: lo.ToPtr(now.Add(-s.Config.Notification.Retention)),
+			OnlyStarred:        lo.ToPtr(false),
+			IncludeSnoozed:     true,
+			IncludeHidden:      true,
+			IncludeRetracted:   true,
+		})
+		if err != nil {
+			log.CtxError(ctx, "[Retention] delete expired notifications failed, err=%v", err)
+			return
+		}
+		log.CtxInfo(ctx, "[Retention] deleted %d expired notifications", n)
+	}
+	if _, err := s.TombstoneMongoMapper.DeleteBefore(ctx, now.Add(-s.Config.Notification.TombstoneRetention)); err != nil {
+		log.CtxError(ctx, "[Retention] delete expired tombstones failed, err=%v", err)
+	}
+}
+
+var RetentionSet = wire.NewSet(
+	NewRetentionService,
+)
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/google/wire"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+type SnoozeService interface {
+	RunWake(ctx context.Context)
+}
+
+type SnoozeServiceImpl struct {
+	Config                  *config.Config
+	NotificationMongoMapper notificationmapper.INotificationMongoMapper
+	DeliveryService         DeliveryService
+}
+
+func NewSnoozeService(config *config.Config, lc *lifecycle.Lifecycle, notificationMongoMapper notificationmapper.INotificationMongoMapper, deliveryService DeliveryService) SnoozeService {
+	s := &SnoozeServiceImpl{
+		Config:                  config,
+		NotificationMongoMapper: notificationMongoMapper,
+		DeliveryService:         deliveryService,
+	}
+	lc.Append(lifecycle.Ticker(identity.SystemContext(), "wake", config.Notification.WakeInterval, s.RunWake))
+	lc.Append(lifecycle.Worker("sortAt", func(<-chan struct{}) {
+		s.backfillSortAt(identity.SystemContext())
+	}))
+	return s
+}
+
+// backfillSortAt 启动后为旧版本写入的通知补上排序时间，补齐之前这些通知不会出现在默认列表和未读数中
+func (s *SnoozeServiceImpl) backfillSortAt(ctx context.Context) {
+	defer metrics.ObserveJob("sortAt", time.Now())
+	n, err := s.NotificationMongoMapper.BackfillSortAt(ctx)
+	if err != nil {
+		log.CtxError(ctx, "[Snooze] backfill sortAt failed, err=%v", err)
+		return
+	}
+	if n > 0 {
+		log.CtxInfo(ctx, "[Snooze] backfilled sortAt of %d notifications", n)
+	}
+}
+
+// RunWake 逐个应用唤醒到期的稍后提醒通知，并按该应用的路由配置重新推送
+func (s *SnoozeServiceImpl) RunWake(ctx context.Context) {
+	defer metrics.ObserveJob("wake", time.Now())
+	appIds, err := s.NotificationMongoMapper.GetAppIds(ctx)
+	if err != nil {
+		log.CtxError(ctx, "[Snooze] get apps failed, err=%v", err)
+		return
+	}
+	// 停止时 ctx 被取消，不再唤醒剩余应用的通知，留到下次启动后处理
+	for _, appId := range appIds {
+		if ctx.Err() != nil {
+			return
+		}
+		s.wake(identity.AppContext(ctx, appId))
+	}
+}
+
+func (s *SnoozeServiceImpl) wake(ctx context.Context) {
+	notifications, err := s.NotificationMongoMapper.Wake(ctx, time.Now())
+	if err != nil {
+		log.CtxError(ctx, "[Snooze] wake notifications failed, err=%v", err)
+		return
+	}
+	for _, n := range notifications {
+		if err = s.DeliveryService.Dispatch(ctx, n); err != nil {
+			log.CtxError(ctx, "[Snooze] dispatch notification %s failed, err=%v", n.ID.Hex(), err)
+		}
+	}
+}
+
+var SnoozeSet = wire.NewSet(
+	NewSnoozeService,
+)
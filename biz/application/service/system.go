@@ -2,21 +2,39 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/convertor"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/limiter"
+	blockmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/block"
 	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	notificationackmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationAck"
 	notificationcountmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationCount"
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
 	slidermapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/slider"
+	tombstonemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/tombstone"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/sensitive"
 	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
 	"github.com/CloudStriver/go-pkg/utils/pconvertor"
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/CloudStriver/service-idl-gen-go/kitex_gen/basic"
 	gensystem "github.com/CloudStriver/service-idl-gen-go/kitex_gen/cloudmind/system"
 	"github.com/google/wire"
 	"github.com/samber/lo"
 	"github.com/zeromicro/go-zero/core/stores/redis"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// backfillCountBatchSize 补建已读记录时每批读出的用户数
+const backfillCountBatchSize = 1000
+
 type SystemService interface {
 	DeleteSlider(ctx context.Context, req *gensystem.DeleteSliderReq) (resp *gensystem.DeleteSliderResp, err error)
 	UpdateSlider(ctx context.Context, req *gensystem.UpdateSliderReq) (resp *gensystem.UpdateSliderResp, err error)
@@ -27,26 +45,96 @@ type SystemService interface {
 	CreateNotifications(ctx context.Context, req *gensystem.CreateNotificationsReq) (resp *gensystem.CreateNotificationsResp, err error)
 	CreateNotificationCount(ctx context.Context, req *gensystem.CreateNotificationCountReq) (resp *gensystem.CreateNotificationCountResp, err error)
 	DeleteNotifications(ctx context.Context, req *gensystem.DeleteNotificationsReq) (resp *gensystem.DeleteNotificationsResp, err error)
+	AckNotifications(ctx context.Context, userId string, notificationIds []string) error
+	GetPendingAckNotifications(ctx context.Context, userId string) ([]*gensystem.Notification, error)
+	GetNotificationAckStats(ctx context.Context, notificationIds []string) ([]*notificationackmapper.AckStat, error)
+	GetNotificationsWithOptions(ctx context.Context, req *gensystem.GetNotificationsReq, opts *GetNotificationsOptions) (resp *gensystem.GetNotificationsResp, err error)
+	StarNotifications(ctx context.Context, userId string, notificationIds []string, starred bool) error
+	SearchNotifications(ctx context.Context, userId string, keyword string, popts *basic.PaginationOptions, cursorType mongop.MongoCursor) (*gensystem.GetNotificationsResp, error)
+	ArchiveNotifications(ctx context.Context, userId string, notificationIds []string, archived bool) error
+	LabelNotifications(ctx context.Context, userId string, notificationIds []string, label string, add bool) error
+	GetUnreadCountByFolder(ctx context.Context, userId string) (*notificationmapper.FolderCount, error)
+	SnoozeNotification(ctx context.Context, userId string, notificationId string, wakeAt time.Time) error
+	UpdateNotifications(ctx context.Context, req *UpdateNotificationsReq) (int64, error)
+	GetPreference(ctx context.Context, userId string) (*preferencemapper.Preference, error)
+	UpdatePreference(ctx context.Context, data *preferencemapper.Preference) error
+	MarkNotificationsRead(ctx context.Context, userId string) error
+	SyncNotifications(ctx context.Context, userId string, version int64) (*SyncResult, error)
+	BlockUser(ctx context.Context, userId string, blockedUserId string) error
+	UnblockUser(ctx context.Context, userId string, blockedUserId string) error
+	GetBlockedUserIds(ctx context.Context, userId string) ([]string, error)
+	BackfillNotificationCounts(ctx context.Context) (int64, error)
+}
+
+// SyncResult 增量同步的结果，Version 在下次同步时传回
+type SyncResult struct {
+	Notifications []*notificationmapper.Notification
+	Tombstones    []*tombstonemapper.Tombstone
+	// ReadAt 已读时间，创建时间晚于它的通知为未读
+	ReadAt  time.Time
+	Version int64
+	// FullResync 为 true 时客户端需要清空本地缓存并重新分页拉取
+	FullResync bool
+}
+
+// UpdateNotificationsReq IDL 中尚未定义 UpdateNotifications，先在服务层给出请求结构，
+// Only 开头的字段为筛选条件，至少需要指定一个，其余非 nil 的字段为要修改的内容
+type UpdateNotificationsReq struct {
+	OnlyNotificationIds []string
+	OnlyUserId          *string
+	OnlyType            *int64
+	OnlySourceContentId *string
+	Text                *string
+	Payload             *string
+	Type                *int64
+}
+
+// GetNotificationsOptions IDL 中的 GetNotificationsReq 尚未包含的筛选条件
+type GetNotificationsOptions struct {
+	OnlyStarred *bool
+	OnlyFolder  *string
+	// CursorType 分页游标的类型，为 nil 时按排序时间分页
+	CursorType mongop.MongoCursor
+	// SkipMarkRead 为 true 时本次查询不会记作已读，需要调用 MarkNotificationsRead
+	SkipMarkRead bool
+}
+
+func (o *GetNotificationsOptions) isEmpty() bool {
+	return o.OnlyStarred == nil && o.OnlyFolder == nil
 }
 
 type SystemServiceImpl struct {
+	Config                       *config.Config
 	NotificationMongoMapper      notificationmapper.INotificationMongoMapper
 	NotificationCountMongoMapper notificationcountmapper.INotificationCountMongoMapper
+	NotificationAckMongoMapper   notificationackmapper.INotificationAckMongoMapper
 	SliderMongoMapper            slidermapper.ISliderMongoMapper
+	PreferenceMongoMapper        preferencemapper.IPreferenceMongoMapper
 	Redis                        *redis.Redis
+	DeliveryService              DeliveryService
+	NotificationLimiter          limiter.INotificationLimiter
+	SensitiveFilter              sensitive.IFilter
+	WebhookService               WebhookService
+	TombstoneMongoMapper         tombstonemapper.ITombstoneMongoMapper
+	BlockMongoMapper             blockmapper.IBlockMongoMapper
+	AnalyticsService             AnalyticsService
+	AuditService                 AuditService
 }
 
 func (s *SystemServiceImpl) DeleteNotifications(ctx context.Context, req *gensystem.DeleteNotificationsReq) (resp *gensystem.DeleteNotificationsResp, err error) {
-	if err = s.NotificationMongoMapper.DeleteNotifications(ctx, &notificationmapper.FilterOptions{
+	if _, err = deleteNotifications(ctx, s.NotificationMongoMapper, s.TombstoneMongoMapper, &notificationmapper.FilterOptions{
 		OnlyUserId:          lo.ToPtr(req.UserId),
 		OnlyNotificationIds: req.NotificationIds,
 		OnlyType:            req.OnlyType,
+		IncludeSnoozed:      true,
 	}); err != nil {
 		return resp, err
 	}
+	s.WebhookService.Publish(ctx, consts.NotificationDeletedHook, req)
 	return resp, nil
 }
 
+// CreateNotificationCount 已读记录会在首次读写时自动创建，保留该接口只为兼容旧的调用方
 func (s *SystemServiceImpl) CreateNotificationCount(ctx context.Context, req *gensystem.CreateNotificationCountReq) (resp *gensystem.CreateNotificationCountResp, err error) {
 	uid, _ := primitive.ObjectIDFromHex(req.UserId)
 	err = s.NotificationCountMongoMapper.CreateNotificationCount(ctx, &notificationcountmapper.NotificationCount{
@@ -65,23 +153,36 @@ func (s *SystemServiceImpl) DeleteSlider(ctx context.Context, req *gensystem.Del
 
 func (s *SystemServiceImpl) UpdateSlider(ctx context.Context, req *gensystem.UpdateSliderReq) (resp *gensystem.UpdateSliderResp, err error) {
 	oid, _ := primitive.ObjectIDFromHex(req.SliderId)
-	if err = s.SliderMongoMapper.UpdateOne(ctx, &slidermapper.Slider{
+	slider := &slidermapper.Slider{
 		ID:       oid,
 		ImageUrl: req.ImageUrl,
 		LinkUrl:  req.LinkUrl,
 		IsPublic: req.IsPublic,
-	}); err != nil {
+	}
+	// 审核标记按更新后的全部链接重新计算，未更新的链接沿用原值
+	old, err := s.SliderMongoMapper.FindOne(ctx, req.SliderId)
+	if err != nil {
+		return resp, err
+	}
+	if slider.NeedReview, err = s.checkSensitiveUrls(lo.Ternary(slider.ImageUrl != "", slider.ImageUrl, old.ImageUrl), lo.Ternary(slider.LinkUrl != "", slider.LinkUrl, old.LinkUrl)); err != nil {
+		return resp, err
+	}
+	if err = s.SliderMongoMapper.UpdateOne(ctx, slider); err != nil {
 		return resp, err
 	}
 	return resp, nil
 }
 
 func (s *SystemServiceImpl) CreateSlider(ctx context.Context, req *gensystem.CreateSliderReq) (resp *gensystem.CreateSliderResp, err error) {
-	if err = s.SliderMongoMapper.InsertOne(ctx, &slidermapper.Slider{
+	slider := &slidermapper.Slider{
 		ImageUrl: req.ImageUrl,
 		LinkUrl:  req.LinkUrl,
 		IsPublic: req.IsPublic,
-	}); err != nil {
+	}
+	if slider.NeedReview, err = s.checkSensitiveUrls(slider.ImageUrl, slider.LinkUrl); err != nil {
+		return resp, err
+	}
+	if err = s.SliderMongoMapper.InsertOne(ctx, slider); err != nil {
 		return resp, err
 	}
 	return resp, nil
@@ -89,6 +190,11 @@ func (s *SystemServiceImpl) CreateSlider(ctx context.Context, req *gensystem.Cre
 
 func (s *SystemServiceImpl) GetSliders(ctx context.Context, req *gensystem.GetSlidersReq) (resp *gensystem.GetSlidersResp, err error) {
 	resp = new(gensystem.GetSlidersResp)
+	placement := "all"
+	if req.OnlyType != nil {
+		placement = strconv.FormatInt(*req.OnlyType, 10)
+	}
+	metrics.SliderRead.Inc(placement)
 	p := pconvertor.PaginationOptionsToModelPaginationOptions(req.PaginationOptions)
 	sliders, total, err := s.SliderMongoMapper.GetSlidersAndCount(ctx, &slidermapper.FilterOptions{
 		OnlyType:     req.OnlyType,
@@ -111,12 +217,25 @@ func (s *SystemServiceImpl) GetSliders(ctx context.Context, req *gensystem.GetSl
 }
 
 func (s *SystemServiceImpl) GetNotifications(ctx context.Context, req *gensystem.GetNotificationsReq) (resp *gensystem.GetNotificationsResp, err error) {
+	return s.GetNotificationsWithOptions(ctx, req, &GetNotificationsOptions{})
+}
+
+// GetNotificationsWithOptions 在 GetNotificationsReq 之外支持更多的筛选条件
+func (s *SystemServiceImpl) GetNotificationsWithOptions(ctx context.Context, req *gensystem.GetNotificationsReq, opts *GetNotificationsOptions) (resp *gensystem.GetNotificationsResp, err error) {
 	resp = new(gensystem.GetNotificationsResp)
 	p := pconvertor.PaginationOptionsToModelPaginationOptions(req.PaginationOptions)
-	notifications, cnt, err := s.NotificationMongoMapper.GetNotificationsAndCount(ctx, &notificationmapper.FilterOptions{
+	fopts := &notificationmapper.FilterOptions{
 		OnlyUserIds: []string{req.UserId, consts.NotificationSystemKey},
 		OnlyType:    req.OnlyType,
-	}, p, mongop.IdCursorType)
+		OnlyStarred: opts.OnlyStarred,
+		OnlyFolder:  opts.OnlyFolder,
+	}
+	// 默认按排序时间分页，被唤醒的通知重新出现在最前
+	sorter := opts.CursorType
+	if sorter == nil {
+		sorter = notificationmapper.SortAtCursorType
+	}
+	notifications, err := s.NotificationMongoMapper.GetNotifications(ctx, fopts, p, sorter)
 	if err != nil {
 		return resp, err
 	}
@@ -127,53 +246,511 @@ func (s *SystemServiceImpl) GetNotifications(ctx context.Context, req *gensystem
 	if p.LastToken != nil {
 		resp.Token = *p.LastToken
 	}
+	s.AnalyticsService.RecordOpen(ctx, req.UserId, notifications)
 
-	if req.OnlyType == nil {
-		uid, _ := primitive.ObjectIDFromHex(req.UserId)
-		if err = s.NotificationCountMongoMapper.UpdateNotificationCount(ctx, &notificationcountmapper.NotificationCount{
-			ID:   uid,
-			Read: cnt,
-		}); err != nil {
+	// 只有查看全部通知时才算作已读
+	if req.OnlyType == nil && opts.isEmpty() && !opts.SkipMarkRead {
+		if err = s.MarkNotificationsRead(ctx, req.UserId); err != nil {
 			return resp, err
 		}
 	}
 	return resp, nil
 }
 
-func (s *SystemServiceImpl) GetNotificationCount(ctx context.Context, req *gensystem.GetNotificationCountReq) (resp *gensystem.GetNotificationCountResp, err error) {
-	cnt, err := s.NotificationMongoMapper.Count(ctx, &notificationmapper.FilterOptions{
-		OnlyUserIds: []string{consts.NotificationSystemKey, req.UserId},
-	})
+// MarkNotificationsRead 把已读时间推进到用户最新一条通知的排序时间，
+// 只有确实有通知从未读变为已读时才推送 notification.read
+func (s *SystemServiceImpl) MarkNotificationsRead(ctx context.Context, userId string) error {
+	latest, err := s.NotificationMongoMapper.GetLatestNotification(ctx, visibleFilter(userId))
+	if errors.Is(err, consts.ErrNotFound) {
+		return nil
+	}
 	if err != nil {
-		return resp, err
+		return err
+	}
+	advanced, err := s.NotificationCountMongoMapper.UpdateReadAt(ctx, userId, latest.SortAt)
+	if err != nil || !advanced {
+		return err
+	}
+	s.WebhookService.Publish(ctx, consts.NotificationReadHook, map[string]any{
+		"userId": userId,
+		"readAt": latest.SortAt.UnixMilli(),
+	})
+	return nil
+}
+
+// SyncNotifications 返回 version（毫秒时间戳）之后新增或修改的通知、被删除通知的墓碑以及当前已读数
+func (s *SystemServiceImpl) SyncNotifications(ctx context.Context, userId string, version int64) (*SyncResult, error) {
+	now := time.Now()
+	res := &SyncResult{Version: now.UnixMilli()}
+	since := time.UnixMilli(version)
+	// 从未同步过或墓碑已被清理时无法给出完整的变更
+	if version <= 0 || since.Before(now.Add(-s.Config.Notification.TombstoneRetention)) {
+		res.FullResync = true
+		return res, nil
 	}
 
-	read, err := s.NotificationCountMongoMapper.GetNotificationCount(ctx, req.UserId)
+	userIds := []string{userId, consts.NotificationSystemKey}
+	fopts := &notificationmapper.FilterOptions{
+		OnlyUserIds:       userIds,
+		OnlyUpdateAtAfter: &since,
+		IncludeSnoozed:    true,
+	}
+	cnt, err := s.NotificationMongoMapper.Count(ctx, fopts)
 	if err != nil {
-		fmt.Println(err)
+		return nil, err
+	}
+	if cnt > s.Config.Notification.SyncMaxItems {
+		res.FullResync = true
+		return res, nil
+	}
+	if res.Notifications, err = s.NotificationMongoMapper.FindMany(ctx, fopts); err != nil {
+		return nil, err
+	}
+	if res.Tombstones, err = s.TombstoneMongoMapper.GetTombstones(ctx, userIds, since); err != nil {
+		return nil, err
+	}
+	if res.ReadAt, err = getReadAt(ctx, s.NotificationMongoMapper, s.NotificationCountMongoMapper, userId); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
 
+func (s *SystemServiceImpl) GetNotificationCount(ctx context.Context, req *gensystem.GetNotificationCountReq) (resp *gensystem.GetNotificationCountResp, err error) {
+	readAt, err := getReadAt(ctx, s.NotificationMongoMapper, s.NotificationCountMongoMapper, req.UserId)
+	if err != nil {
+		return resp, err
+	}
+	cnt, err := s.NotificationMongoMapper.Count(ctx, unreadFilter(req.UserId, readAt))
+	if err != nil {
 		return resp, err
 	}
 	return &gensystem.GetNotificationCountResp{
-		Total: cnt - read,
+		Total: cnt,
 	}, nil
 }
 
 func (s *SystemServiceImpl) CreateNotifications(ctx context.Context, req *gensystem.CreateNotificationsReq) (resp *gensystem.CreateNotificationsResp, err error) {
-	if err = s.NotificationMongoMapper.InsertOne(ctx, &notificationmapper.Notification{
+	notification := &notificationmapper.Notification{
 		TargetUserId:    req.TargetUserId,
 		SourceUserId:    req.SourceUserId,
 		SourceContentId: req.SourceContentId,
 		Type:            req.Type,
 		TargetType:      req.TargetType,
 		Text:            req.Text,
-	}); err != nil {
+		NeedAck:         lo.Contains(s.Config.AckTypesOf(identity.FromContext(ctx).AppId), req.Type),
+	}
+	if blocked, err := s.checkBlocked(ctx, notification); err != nil || blocked {
+		return resp, err
+	}
+	if notification.NeedReview, err = s.checkSensitive(&notification.Text); err != nil {
+		return resp, err
+	}
+	if ok, err := s.checkRateLimit(ctx, notification); err != nil || !ok {
+		return resp, err
+	}
+	if err = s.NotificationMongoMapper.InsertOne(ctx, notification); err != nil {
 		return resp, err
 	}
+	metrics.NotificationCreated.Inc(strconv.FormatInt(notification.Type, 10))
+	// 通知已经写入，站外投递失败不能让调用方重试，否则会产生重复通知
+	if err = s.DeliveryService.Dispatch(ctx, notification); err != nil {
+		log.CtxError(ctx, "[Delivery] dispatch notification %s failed, err=%v", notification.ID.Hex(), err)
+	}
+	s.WebhookService.Publish(ctx, consts.NotificationCreatedHook, convertor.NotificationMapperToNotification(notification))
 
 	return resp, nil
 }
 
+// checkSensitive 按配置的模式处理文本中的敏感词：拒绝、原地打码，或保留原文并返回需要审核
+func (s *SystemServiceImpl) checkSensitive(texts ...*string) (needReview bool, err error) {
+	for _, text := range texts {
+		if !s.SensitiveFilter.Contains(*text) {
+			continue
+		}
+		switch s.Config.Sensitive.Mode {
+		case consts.SensitiveReject:
+			return false, consts.ErrSensitiveText
+		case consts.SensitiveMask:
+			*text = s.SensitiveFilter.Mask(*text)
+		case consts.SensitiveReview:
+			needReview = true
+		}
+	}
+	return needReview, nil
+}
+
+// checkSensitiveUrls 链接打码后就无法访问，因此打码模式下改为标记需要审核
+func (s *SystemServiceImpl) checkSensitiveUrls(urls ...string) (needReview bool, err error) {
+	for _, url := range urls {
+		if !s.SensitiveFilter.Contains(url) {
+			continue
+		}
+		if s.Config.Sensitive.Mode == consts.SensitiveReject {
+			return false, consts.ErrSensitiveText
+		}
+		needReview = true
+	}
+	return needReview, nil
+}
+
+// checkRateLimit 检查来源用户是否超过通知频率限制，超限的通知按配置丢弃或合并
+func (s *SystemServiceImpl) checkRateLimit(ctx context.Context, notification *notificationmapper.Notification) (bool, error) {
+	if notification.SourceUserId == "" {
+		return true, nil
+	}
+	ok, err := s.NotificationLimiter.Allow(ctx, notification.SourceUserId, notification.TargetUserId, notification.Type)
+	if err != nil {
+		// 限流依赖的 Redis 不可用时放行，避免影响正常通知
+		log.CtxError(ctx, "[RateLimit] check rate limit failed, err=%v", err)
+		return true, nil
+	}
+	if ok {
+		return true, nil
+	}
+
+	action := s.Config.RateLimit.Action
+	if action == consts.RateLimitMerge {
+		if _, err = s.NotificationMongoMapper.MergeLatest(ctx, &notificationmapper.FilterOptions{
+			OnlyUserId:       lo.ToPtr(notification.TargetUserId),
+			OnlySourceUserId: lo.ToPtr(notification.SourceUserId),
+			OnlyType:         lo.ToPtr(notification.Type),
+		}); err != nil {
+			return false, err
+		}
+	}
+	metrics.NotificationRateLimited.Inc(strconv.FormatInt(notification.Type, 10), action)
+	return false, nil
+}
+
+// checkBlocked 通知的目标用户屏蔽了来源用户时丢弃该通知
+func (s *SystemServiceImpl) checkBlocked(ctx context.Context, notification *notificationmapper.Notification) (bool, error) {
+	if notification.SourceUserId == "" || notification.TargetUserId == consts.NotificationSystemKey {
+		return false, nil
+	}
+	blocked, err := s.BlockMongoMapper.IsBlocked(ctx, notification.TargetUserId, notification.SourceUserId)
+	if err != nil {
+		return false, err
+	}
+	if blocked {
+		log.CtxInfo(ctx, "[Block] drop notification from %s to %s", notification.SourceUserId, notification.TargetUserId)
+	}
+	return blocked, nil
+}
+
+// AckNotifications 用户确认需要确认的通知
+func (s *SystemServiceImpl) AckNotifications(ctx context.Context, userId string, notificationIds []string) error {
+	notifications, err := s.NotificationMongoMapper.FindMany(ctx, &notificationmapper.FilterOptions{
+		OnlyUserIds:         []string{userId, consts.NotificationSystemKey},
+		OnlyNotificationIds: notificationIds,
+		OnlyNeedAck:         lo.ToPtr(true),
+	})
+	if err != nil {
+		return err
+	}
+	if len(notifications) != len(lo.Uniq(notificationIds)) {
+		return consts.ErrNotFound
+	}
+	err = s.NotificationAckMongoMapper.Ack(ctx, userId, notificationIds)
+	s.AuditService.Record(ctx, "AckNotifications", append([]string{userId}, notificationIds...), nil, nil, err)
+	return err
+}
+
+// GetPendingAckNotifications 获取用户尚未确认的通知，登录时调用
+func (s *SystemServiceImpl) GetPendingAckNotifications(ctx context.Context, userId string) ([]*gensystem.Notification, error) {
+	notifications, err := s.NotificationMongoMapper.FindMany(ctx, &notificationmapper.FilterOptions{
+		OnlyUserIds: []string{userId, consts.NotificationSystemKey},
+		OnlyNeedAck: lo.ToPtr(true),
+	})
+	if err != nil || len(notifications) == 0 {
+		return nil, err
+	}
+	acked, err := s.NotificationAckMongoMapper.GetAckedNotificationIds(ctx, userId,
+		lo.Map[*notificationmapper.Notification, string](notifications, func(item *notificationmapper.Notification, _ int) string {
+			return item.ID.Hex()
+		}))
+	if err != nil {
+		return nil, err
+	}
+	return lo.FilterMap[*notificationmapper.Notification, *gensystem.Notification](notifications,
+		func(item *notificationmapper.Notification, _ int) (*gensystem.Notification, bool) {
+			if lo.Contains(acked, item.ID.Hex()) {
+				return nil, false
+			}
+			return convertor.NotificationMapperToNotification(item), true
+		}), nil
+}
+
+// GetNotificationAckStats 管理端查询每条通知的确认人数与确认时间
+func (s *SystemServiceImpl) GetNotificationAckStats(ctx context.Context, notificationIds []string) ([]*notificationackmapper.AckStat, error) {
+	return s.NotificationAckMongoMapper.GetAckStats(ctx, notificationIds)
+}
+
+// StarNotifications 收藏或取消收藏用户自己的通知，收藏的通知不会被过期清理
+func (s *SystemServiceImpl) StarNotifications(ctx context.Context, userId string, notificationIds []string, starred bool) error {
+	if len(notificationIds) == 0 {
+		return consts.ErrInvalidArgument
+	}
+	n, err := s.NotificationMongoMapper.UpdateNotifications(ctx, &notificationmapper.FilterOptions{
+		OnlyUserId:          lo.ToPtr(userId),
+		OnlyNotificationIds: notificationIds,
+	}, &notificationmapper.UpdateOptions{
+		IsStarred: lo.ToPtr(starred),
+	})
+	s.AuditService.Record(ctx, "StarNotifications", append([]string{userId}, notificationIds...), nil,
+		map[string]any{"starred": starred, "count": n}, err)
+	return err
+}
+
+// SearchNotifications 在用户自己和全站广播的通知中检索文案，分页方式与 GetNotifications 相同，cursorType 为 nil 时按 id 分页
+func (s *SystemServiceImpl) SearchNotifications(ctx context.Context, userId string, keyword string, popts *basic.PaginationOptions, cursorType mongop.MongoCursor) (*gensystem.GetNotificationsResp, error) {
+	keyword = strings.TrimSpace(keyword)
+	if keyword == "" {
+		return nil, consts.ErrInvalidArgument
+	}
+	if cursorType == nil {
+		cursorType = mongop.IdCursorType
+	}
+	resp := new(gensystem.GetNotificationsResp)
+	p := pconvertor.PaginationOptionsToModelPaginationOptions(popts)
+	notifications, err := s.NotificationMongoMapper.GetNotifications(ctx, &notificationmapper.FilterOptions{
+		OnlyUserIds: []string{userId, consts.NotificationSystemKey},
+		OnlyKeyword: lo.ToPtr(keyword),
+	}, p, cursorType)
+	if err != nil {
+		return nil, err
+	}
+	resp.Notifications = lo.Map[*notificationmapper.Notification, *gensystem.Notification](notifications,
+		func(item *notificationmapper.Notification, _ int) *gensystem.Notification {
+			return convertor.NotificationMapperToNotification(item)
+		})
+	if p.LastToken != nil {
+		resp.Token = *p.LastToken
+	}
+	return resp, nil
+}
+
+// ArchiveNotifications 把用户自己的通知移入或移出归档
+func (s *SystemServiceImpl) ArchiveNotifications(ctx context.Context, userId string, notificationIds []string, archived bool) error {
+	if len(notificationIds) == 0 {
+		return consts.ErrInvalidArgument
+	}
+	n, err := s.NotificationMongoMapper.UpdateNotifications(ctx, &notificationmapper.FilterOptions{
+		OnlyUserId:          lo.ToPtr(userId),
+		OnlyNotificationIds: notificationIds,
+	}, &notificationmapper.UpdateOptions{
+		IsArchived: lo.ToPtr(archived),
+	})
+	s.AuditService.Record(ctx, "ArchiveNotifications", append([]string{userId}, notificationIds...), nil,
+		map[string]any{"archived": archived, "count": n}, err)
+	return err
+}
+
+// LabelNotifications 给用户自己的通知添加或移除自定义标签
+func (s *SystemServiceImpl) LabelNotifications(ctx context.Context, userId string, notificationIds []string, label string, add bool) error {
+	if len(notificationIds) == 0 || label == "" || len([]rune(label)) > s.Config.Notification.MaxLabelLength ||
+		label == consts.InboxFolder || label == consts.ArchivedFolder {
+		return consts.ErrInvalidArgument
+	}
+	fopts := &notificationmapper.FilterOptions{
+		OnlyUserId:          lo.ToPtr(userId),
+		OnlyNotificationIds: notificationIds,
+	}
+	uopts := &notificationmapper.UpdateOptions{}
+	if add {
+		// 标签数已满的通知不再添加
+		fopts.OnlyLabelsFewerThan = lo.ToPtr(s.Config.Notification.MaxLabels)
+		uopts.AddLabel = lo.ToPtr(label)
+	} else {
+		uopts.RemoveLabel = lo.ToPtr(label)
+	}
+	n, err := s.NotificationMongoMapper.UpdateNotifications(ctx, fopts, uopts)
+	s.AuditService.Record(ctx, "LabelNotifications", append([]string{userId}, notificationIds...), nil,
+		map[string]any{"label": label, "add": add, "count": n}, err)
+	return err
+}
+
+// GetUnreadCountByFolder 统计各文件夹的未读数
+func (s *SystemServiceImpl) GetUnreadCountByFolder(ctx context.Context, userId string) (*notificationmapper.FolderCount, error) {
+	readAt, err := getReadAt(ctx, s.NotificationMongoMapper, s.NotificationCountMongoMapper, userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.NotificationMongoMapper.CountByFolder(ctx, unreadFilter(userId, readAt))
+}
+
+// SnoozeNotification 稍后提醒，通知在 wakeAt 之前不出现在列表和未读数中，之后作为新的未读通知重新出现
+func (s *SystemServiceImpl) SnoozeNotification(ctx context.Context, userId string, notificationId string, wakeAt time.Time) error {
+	if !wakeAt.After(time.Now()) {
+		return consts.ErrInvalidArgument
+	}
+	// 已经在稍后提醒中的通知可以修改提醒时间
+	n, err := s.NotificationMongoMapper.UpdateNotifications(ctx, &notificationmapper.FilterOptions{
+		OnlyUserId:          lo.ToPtr(userId),
+		OnlyNotificationIds: []string{notificationId},
+		IncludeSnoozed:      true,
+	}, &notificationmapper.UpdateOptions{
+		SnoozeUntil: lo.ToPtr(wakeAt),
+	})
+	s.AuditService.Record(ctx, "SnoozeNotification", []string{userId, notificationId}, nil,
+		map[string]any{"wakeAt": wakeAt, "count": n}, err)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return consts.ErrNotFound
+	}
+	return nil
+}
+
+// UpdateNotifications 修改已发送通知的文案、附加数据或类型，可以按 id 或其他条件批量修改
+func (s *SystemServiceImpl) UpdateNotifications(ctx context.Context, req *UpdateNotificationsReq) (n int64, err error) {
+	fopts := &notificationmapper.FilterOptions{
+		OnlyNotificationIds: req.OnlyNotificationIds,
+		OnlyUserId:          req.OnlyUserId,
+		OnlyType:            req.OnlyType,
+		OnlySourceContentId: req.OnlySourceContentId,
+		IncludeSnoozed:      true,
+		IncludeHidden:       true,
+		IncludeRetracted:    true,
+	}
+	uopts := &notificationmapper.UpdateOptions{
+		Text:    req.Text,
+		Payload: req.Payload,
+		Type:    req.Type,
+	}
+	// 不允许无条件修改全部通知
+	if notificationmapper.IsEmptyFilter(fopts) {
+		return 0, consts.ErrInvalidArgument
+	}
+	if uopts.Text == nil && uopts.Payload == nil && uopts.Type == nil {
+		return 0, nil
+	}
+	if uopts.Text != nil {
+		needReview, err := s.checkSensitive(uopts.Text)
+		if err != nil {
+			return 0, err
+		}
+		if needReview {
+			uopts.NeedReview = lo.ToPtr(true)
+		}
+	}
+	n, err = s.NotificationMongoMapper.UpdateNotifications(ctx, fopts, uopts)
+	s.AuditService.Record(ctx, "UpdateNotifications", req.OnlyNotificationIds, nil, map[string]any{"request": req, "count": n}, err)
+	return n, err
+}
+
+func (s *SystemServiceImpl) GetPreference(ctx context.Context, userId string) (*preferencemapper.Preference, error) {
+	return s.PreferenceMongoMapper.GetPreference(ctx, userId)
+}
+
+func (s *SystemServiceImpl) UpdatePreference(ctx context.Context, data *preferencemapper.Preference) error {
+	if data.DigestFrequency != 0 && data.DigestFrequency != consts.DigestFrequencyDaily && data.DigestFrequency != consts.DigestFrequencyWeekly {
+		return consts.ErrInvalidArgument
+	}
+	if data.Email != "" && !isPlainEmail(data.Email) {
+		return consts.ErrInvalidArgument
+	}
+	if _, err := time.LoadLocation(data.TimeZone); err != nil {
+		return consts.ErrInvalidArgument
+	}
+	if (data.QuietStart == "") != (data.QuietEnd == "") {
+		return consts.ErrInvalidArgument
+	}
+	for _, t := range lo.Compact([]string{data.QuietStart, data.QuietEnd}) {
+		if _, err := time.Parse(consts.QuietHoursLayout, t); err != nil {
+			return consts.ErrInvalidArgument
+		}
+	}
+	userId := data.ID.Hex()
+	// 首次设置时没有旧记录
+	var before any
+	if old, err := s.PreferenceMongoMapper.GetPreference(ctx, userId); err == nil {
+		before = old
+	} else if !errors.Is(err, consts.ErrNotFound) {
+		return err
+	}
+	err := s.PreferenceMongoMapper.UpsertPreference(ctx, data)
+	s.AuditService.Record(ctx, "UpdatePreference", []string{userId}, before, data, err)
+	return err
+}
+
+// isPlainEmail 只接受不带显示名的单个地址，地址会原样写入邮件头，不能包含换行
+func isPlainEmail(email string) bool {
+	if strings.ContainsAny(email, "\r\n") {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// BlockUser 屏蔽来源用户，之后不再接收其触发的通知，已有的通知也会被隐藏
+func (s *SystemServiceImpl) BlockUser(ctx context.Context, userId string, blockedUserId string) (err error) {
+	if userId == "" || blockedUserId == "" || userId == blockedUserId {
+		return consts.ErrInvalidArgument
+	}
+	defer func() {
+		s.AuditService.Record(ctx, "BlockUser", []string{userId, blockedUserId}, nil, nil, err)
+	}()
+	if err = s.BlockMongoMapper.Block(ctx, userId, blockedUserId); err != nil {
+		return err
+	}
+	_, err = hideNotifications(ctx, s.NotificationMongoMapper, s.TombstoneMongoMapper, &notificationmapper.FilterOptions{
+		OnlyUserId:       lo.ToPtr(userId),
+		OnlySourceUserId: lo.ToPtr(blockedUserId),
+		IncludeSnoozed:   true,
+	}, &notificationmapper.UpdateOptions{
+		IsHidden: lo.ToPtr(true),
+	})
+	return err
+}
+
+// UnblockUser 取消屏蔽，并恢复之前被隐藏的通知
+func (s *SystemServiceImpl) UnblockUser(ctx context.Context, userId string, blockedUserId string) (err error) {
+	if userId == "" || blockedUserId == "" {
+		return consts.ErrInvalidArgument
+	}
+	defer func() {
+		s.AuditService.Record(ctx, "UnblockUser", []string{userId, blockedUserId}, nil, nil, err)
+	}()
+	if err = s.BlockMongoMapper.Unblock(ctx, userId, blockedUserId); err != nil {
+		return err
+	}
+	_, err = s.NotificationMongoMapper.UpdateNotifications(ctx, &notificationmapper.FilterOptions{
+		OnlyUserId:       lo.ToPtr(userId),
+		OnlySourceUserId: lo.ToPtr(blockedUserId),
+		IncludeSnoozed:   true,
+		IncludeHidden:    true,
+	}, &notificationmapper.UpdateOptions{
+		IsHidden: lo.ToPtr(false),
+	})
+	return err
+}
+
+func (s *SystemServiceImpl) GetBlockedUserIds(ctx context.Context, userId string) ([]string, error) {
+	return s.BlockMongoMapper.GetBlockedUserIds(ctx, userId)
+}
+
+// BackfillNotificationCounts 为收到过通知但缺少已读记录的用户补建记录，逐个应用处理，
+// 用户只会得到其收到过通知的应用下的记录
+func (s *SystemServiceImpl) BackfillNotificationCounts(ctx context.Context) (int64, error) {
+	appIds, err := s.NotificationMongoMapper.GetAppIds(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, appId := range appIds {
+		appCtx := identity.AppContext(ctx, appId)
+		if err = s.NotificationMongoMapper.ScanTargetUserIds(appCtx, backfillCountBatchSize, func(userIds []string) error {
+			n, err := s.NotificationCountMongoMapper.EnsureNotificationCounts(appCtx, userIds)
+			total += n
+			return err
+		}); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
 var SystemSet = wire.NewSet(
 	wire.Struct(new(SystemServiceImpl), "*"),
 	wire.Bind(new(SystemService), new(*SystemServiceImpl)),
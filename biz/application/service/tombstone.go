@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	tombstonemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/tombstone"
+)
+
+// tombstoneBatchSize 批量删除、隐藏时每批处理的通知数，避免一次读入全部通知或超过单条命令的大小限制
+const tombstoneBatchSize = 1000
+
+// deleteNotifications 分批删除满足条件的通知，并为每条通知留下墓碑供客户端增量同步，返回已删除的数量
+func deleteNotifications(ctx context.Context, notificationMongoMapper notificationmapper.INotificationMongoMapper,
+	tombstoneMongoMapper tombstonemapper.ITombstoneMongoMapper, fopts *notificationmapper.FilterOptions) (int64, error) {
+	return forEachBatch(ctx, notificationMongoMapper, tombstoneMongoMapper, fopts, func(ids []string) error {
+		return notificationMongoMapper.DeleteNotifications(ctx, batchFilter(ids))
+	})
+}
+
+// hideNotifications 按 uopts 分批把满足条件的通知标记为隐藏或撤回，对客户端而言等同于删除，同样留下墓碑
+func hideNotifications(ctx context.Context, notificationMongoMapper notificationmapper.INotificationMongoMapper,
+	tombstoneMongoMapper tombstonemapper.ITombstoneMongoMapper, fopts *notificationmapper.FilterOptions, uopts *notificationmapper.UpdateOptions) (int64, error) {
+	return forEachBatch(ctx, notificationMongoMapper, tombstoneMongoMapper, fopts, func(ids []string) error {
+		_, err := notificationMongoMapper.UpdateNotifications(ctx, batchFilter(ids), uopts)
+		return err
+	})
+}
+
+// forEachBatch 按 _id 顺序每次取出一批满足条件的通知交给 apply 处理并写入墓碑，直到没有剩余；
+// ctx 被取消（后台任务停止或请求超时）时在两批之间返回，剩余的通知留到下次处理
+func forEachBatch(ctx context.Context, notificationMongoMapper notificationmapper.INotificationMongoMapper,
+	tombstoneMongoMapper tombstonemapper.ITombstoneMongoMapper, fopts *notificationmapper.FilterOptions, apply func(ids []string) error) (int64, error) {
+	var (
+		total int64
+		after primitive.ObjectID
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		notifications, err := notificationMongoMapper.FindBatch(ctx, fopts, after, tombstoneBatchSize)
+		if err != nil || len(notifications) == 0 {
+			return total, err
+		}
+		ids := lo.Map[*notificationmapper.Notification, string](notifications, func(item *notificationmapper.Notification, _ int) string {
+			return item.ID.Hex()
+		})
+		if err = apply(ids); err != nil {
+			return total, err
+		}
+		if err = tombstoneMongoMapper.InsertMany(ctx, makeTombstones(notifications)); err != nil {
+			return total, err
+		}
+		total += int64(len(notifications))
+		if len(notifications) < tombstoneBatchSize {
+			return total, nil
+		}
+		after = notifications[len(notifications)-1].ID
+	}
+}
+
+func batchFilter(ids []string) *notificationmapper.FilterOptions {
+	return &notificationmapper.FilterOptions{
+		OnlyNotificationIds: ids,
+		IncludeSnoozed:      true,
+		IncludeHidden:       true,
+		IncludeRetracted:    true,
+	}
+}
+
+func makeTombstones(notifications []*notificationmapper.Notification) []*tombstonemapper.Tombstone {
+	return lo.Map[*notificationmapper.Notification, *tombstonemapper.Tombstone](notifications,
+		func(item *notificationmapper.Notification, _ int) *tombstonemapper.Tombstone {
+			return &tombstonemapper.Tombstone{
+				NotificationId: item.ID.Hex(),
+				TargetUserId:   item.TargetUserId,
+				AppId:          item.AppId,
+			}
+		})
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	notificationmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	notificationcountmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationCount"
+)
+
+// visibleFilter 用户能看到的全部通知，包括全站广播
+func visibleFilter(userId string) *notificationmapper.FilterOptions {
+	return &notificationmapper.FilterOptions{
+		OnlyUserIds: []string{userId, consts.NotificationSystemKey},
+	}
+}
+
+// getReadAt 用户的已读时间，排序时间晚于它的通知为未读，通知被删除、归档或隐藏都不会影响其他通知的已读状态。
+// 旧版本只记录了已读数（按 id 倒序的前 总数-已读数 条为未读），第一次读取时换算为已读时间并写回
+func getReadAt(ctx context.Context, notificationMongoMapper notificationmapper.INotificationMongoMapper,
+	notificationCountMongoMapper notificationcountmapper.INotificationCountMongoMapper, userId string) (time.Time, error) {
+	state, err := notificationCountMongoMapper.GetReadState(ctx, userId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !state.ReadAt.IsZero() || state.Read == 0 {
+		return state.ReadAt, nil
+	}
+
+	fopts := visibleFilter(userId)
+	total, err := notificationMongoMapper.Count(ctx, fopts)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var lastRead *notificationmapper.Notification
+	if unread := total - state.Read; unread <= 0 {
+		lastRead, err = notificationMongoMapper.GetLatestNotification(ctx, fopts)
+	} else {
+		lastRead, err = notificationMongoMapper.GetNthNotification(ctx, fopts, unread+1)
+	}
+	switch {
+	case errors.Is(err, consts.ErrNotFound):
+		return time.Time{}, nil
+	case err != nil:
+		return time.Time{}, err
+	}
+	if _, err = notificationCountMongoMapper.UpdateReadAt(ctx, userId, lastRead.SortAt); err != nil {
+		return time.Time{}, err
+	}
+	return lastRead.SortAt, nil
+}
+
+// unreadFilter 用户排序时间晚于已读时间的可见通知，被唤醒的通知排序时间更新后重新计入未读
+func unreadFilter(userId string, readAt time.Time) *notificationmapper.FilterOptions {
+	fopts := visibleFilter(userId)
+	fopts.OnlySortAtAfter = &readAt
+	return fopts
+}
@@ -0,0 +1,324 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination"
+	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/google/wire"
+	"github.com/samber/lo"
+	"github.com/zeromicro/go-zero/core/collection"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	webhookmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/webhook"
+	webhookdeliverymapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/webhookDelivery"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	webhookEventHeader     = "X-CloudMind-Event"
+	webhookDeliveryHeader  = "X-CloudMind-Delivery"
+	webhookSignatureHeader = "X-CloudMind-Signature"
+	webhookCacheName       = "webhook"
+)
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, data *webhookmapper.Webhook) (string, error)
+	UpdateWebhook(ctx context.Context, data *webhookmapper.Webhook) error
+	DeleteWebhook(ctx context.Context, webhookId string) error
+	GetWebhooks(ctx context.Context) ([]*webhookmapper.Webhook, error)
+	GetWebhookDeliveries(ctx context.Context, webhookId string, popts *pagination.PaginationOptions) ([]*webhookdeliverymapper.WebhookDelivery, error)
+	Publish(ctx context.Context, event string, data any)
+	RunDueWebhooks(ctx context.Context)
+}
+
+type WebhookServiceImpl struct {
+	Config                     *config.Config
+	WebhookMongoMapper         webhookmapper.IWebhookMongoMapper
+	WebhookDeliveryMongoMapper webhookdeliverymapper.IWebhookDeliveryMongoMapper
+	AuditService               AuditService
+	client                     *http.Client
+	// webhooks 各应用的订阅列表，Publish 在每次创建、已读通知时都会调用，短时间缓存避免每次查询 Mongo
+	webhooks *collection.Cache
+}
+
+type webhookPayload struct {
+	Id      string `json:"id"`
+	Event   string `json:"event"`
+	OccurAt int64  `json:"occurAt"`
+	Data    any    `json:"data"`
+}
+
+func NewWebhookService(config *config.Config, lc *lifecycle.Lifecycle, webhookMongoMapper webhookmapper.IWebhookMongoMapper,
+	webhookDeliveryMongoMapper webhookdeliverymapper.IWebhookDeliveryMongoMapper, auditService AuditService) (WebhookService, error) {
+	webhooks, err := collection.NewCache(config.Webhook.CacheTTL, collection.WithName(webhookCacheName))
+	if err != nil {
+		return nil, err
+	}
+	s := &WebhookServiceImpl{
+		Config:                     config,
+		WebhookMongoMapper:         webhookMongoMapper,
+		WebhookDeliveryMongoMapper: webhookDeliveryMongoMapper,
+		AuditService:               auditService,
+		webhooks:                   webhooks,
+	}
+	// 连接时校验实际拨号的地址，域名在注册后被解析到内网时同样会被拒绝
+	s.client = &http.Client{
+		Timeout: config.Webhook.Timeout,
+		Transport: &http.Transport{
+			DialContext: (&net.Dialer{
+				Timeout: config.Webhook.Timeout,
+				Control: s.checkDialAddress,
+			}).DialContext,
+			TLSHandshakeTimeout: config.Webhook.Timeout,
+		},
+	}
+	lc.Append(lifecycle.Ticker(identity.SystemContext(), "webhook", config.Webhook.Interval, s.RunDueWebhooks))
+	return s, nil
+}
+
+func (s *WebhookServiceImpl) CreateWebhook(ctx context.Context, data *webhookmapper.Webhook) (string, error) {
+	if data.Secret == "" || !s.isValidWebhookUrl(ctx, data.Url) || !isValidWebhookEvents(data.Events) {
+		return "", consts.ErrInvalidArgument
+	}
+	err := s.WebhookMongoMapper.InsertOne(ctx, data)
+	s.webhooks.Del(webhookCacheKey(ctx))
+	s.AuditService.Record(ctx, "CreateWebhook", []string{data.ID.Hex()}, nil, data, err)
+	if err != nil {
+		return "", err
+	}
+	return data.ID.Hex(), nil
+}
+
+// UpdateWebhook 只更新并校验传入的字段，零值表示保持不变，因此无法把 Secret 改为空
+func (s *WebhookServiceImpl) UpdateWebhook(ctx context.Context, data *webhookmapper.Webhook) error {
+	if data.Url != "" && !s.isValidWebhookUrl(ctx, data.Url) {
+		return consts.ErrInvalidArgument
+	}
+	if len(data.Events) > 0 && !isValidWebhookEvents(data.Events) {
+		return consts.ErrInvalidArgument
+	}
+	before := s.webhookSnapshot(ctx, data.ID.Hex())
+	err := s.WebhookMongoMapper.UpdateOne(ctx, data)
+	s.webhooks.Del(webhookCacheKey(ctx))
+	s.AuditService.Record(ctx, "UpdateWebhook", []string{data.ID.Hex()}, before, data, err)
+	return err
+}
+
+func (s *WebhookServiceImpl) DeleteWebhook(ctx context.Context, webhookId string) error {
+	before := s.webhookSnapshot(ctx, webhookId)
+	err := s.WebhookMongoMapper.DeleteOne(ctx, webhookId)
+	s.webhooks.Del(webhookCacheKey(ctx))
+	s.AuditService.Record(ctx, "DeleteWebhook", []string{webhookId}, before, nil, err)
+	return err
+}
+
+// webhookSnapshot 审计用的修改前快照，Secret 不会被序列化，查询失败时不记录快照
+func (s *WebhookServiceImpl) webhookSnapshot(ctx context.Context, webhookId string) any {
+	w, err := s.WebhookMongoMapper.FindOne(ctx, webhookId)
+	if err != nil {
+		return nil
+	}
+	return w
+}
+
+func (s *WebhookServiceImpl) GetWebhooks(ctx context.Context) ([]*webhookmapper.Webhook, error) {
+	return s.WebhookMongoMapper.GetWebhooks(ctx)
+}
+
+// GetWebhookDeliveries 分页查询某个订阅的推送记录，最新的在前
+func (s *WebhookServiceImpl) GetWebhookDeliveries(ctx context.Context, webhookId string, popts *pagination.PaginationOptions) ([]*webhookdeliverymapper.WebhookDelivery, error) {
+	return s.WebhookDeliveryMongoMapper.GetDeliveries(ctx, webhookId, popts, mongop.IdCursorType)
+}
+
+// Publish 为订阅了该事件的每个 webhook 写入一条待推送记录，失败只记录日志，不影响主流程。
+// 订阅列表按应用缓存 CacheTTL，其他实例上的订阅变更最多延迟这么久生效
+func (s *WebhookServiceImpl) Publish(ctx context.Context, event string, data any) {
+	v, err := s.webhooks.Take(webhookCacheKey(ctx), func() (any, error) {
+		return s.WebhookMongoMapper.GetWebhooks(ctx)
+	})
+	if err != nil {
+		log.CtxError(ctx, "[Webhook] get webhooks of %s failed, err=%v", event, err)
+		return
+	}
+	webhooks := lo.Filter(v.([]*webhookmapper.Webhook), func(w *webhookmapper.Webhook, _ int) bool {
+		return lo.Contains(w.Events, event)
+	})
+	if len(webhooks) == 0 {
+		return
+	}
+	now := time.Now()
+	deliveries := make([]*webhookdeliverymapper.WebhookDelivery, 0, len(webhooks))
+	for _, w := range webhooks {
+		id := primitive.NewObjectID()
+		payload, err := json.Marshal(&webhookPayload{
+			Id:      id.Hex(),
+			Event:   event,
+			OccurAt: now.UnixMilli(),
+			Data:    data,
+		})
+		if err != nil {
+			log.CtxError(ctx, "[Webhook] marshal payload of %s failed, err=%v", event, err)
+			return
+		}
+		deliveries = append(deliveries, &webhookdeliverymapper.WebhookDelivery{
+			ID:            id,
+			WebhookId:     w.ID.Hex(),
+			Event:         event,
+			Payload:       string(payload),
+			Status:        consts.DeliveryStatusPending,
+			NextAttemptAt: now,
+		})
+	}
+	if err = s.WebhookDeliveryMongoMapper.InsertMany(ctx, deliveries); err != nil {
+		log.CtxError(ctx, "[Webhook] save deliveries of %s failed, err=%v", event, err)
+	}
+}
+
+// RunDueWebhooks 依次领取并推送所有应用到期的记录，每条记录只会推送给所属应用的订阅，失败时按指数退避重试
+func (s *WebhookServiceImpl) RunDueWebhooks(ctx context.Context) {
+	defer metrics.ObserveJob("webhook", time.Now())
+	// 停止时 ctx 被取消，不再领取新的投递，未完成的投递在租约到期后由其他实例重新领取
+	for ctx.Err() == nil {
+		d, err := s.WebhookDeliveryMongoMapper.ClaimDue(ctx, time.Now(), s.Config.Webhook.Lease)
+		if errors.Is(err, consts.ErrNotFound) {
+			return
+		}
+		if err != nil {
+			log.CtxError(ctx, "[Webhook] claim due delivery failed, err=%v", err)
+			return
+		}
+		s.attempt(identity.AppContext(ctx, d.AppId), d)
+	}
+}
+
+func (s *WebhookServiceImpl) attempt(ctx context.Context, d *webhookdeliverymapper.WebhookDelivery) {
+	w, err := s.WebhookMongoMapper.FindOne(ctx, d.WebhookId)
+	if errors.Is(err, consts.ErrNotFound) {
+		// 订阅已被删除，不再推送
+		d.Status = consts.DeliveryStatusFailed
+		d.LastError = "webhook deleted"
+	} else if err == nil {
+		d.Attempts++
+		d.ResponseCode, err = s.post(ctx, w, d)
+		switch {
+		case err == nil:
+			d.Status = consts.DeliveryStatusSuccess
+		case d.Attempts >= s.Config.Webhook.MaxAttempts:
+			d.Status = consts.DeliveryStatusFailed
+			d.LastError = err.Error()
+		default:
+			d.LastError = err.Error()
+			d.NextAttemptAt = time.Now().Add(exponentialBackoff(s.Config.Webhook.Backoff, s.Config.Webhook.MaxBackoff, d.Attempts))
+		}
+	} else {
+		log.CtxError(ctx, "[Webhook] get webhook %s failed, err=%v", d.WebhookId, err)
+		return
+	}
+	if err = s.WebhookDeliveryMongoMapper.UpdateOne(ctx, d); err != nil {
+		log.CtxError(ctx, "[Webhook] update delivery %s failed, err=%v", d.ID.Hex(), err)
+	}
+}
+
+// post 以 HMAC-SHA256 签名推送一次，非 2xx 响应视为失败
+func (s *WebhookServiceImpl) post(ctx context.Context, w *webhookmapper.Webhook, d *webhookdeliverymapper.WebhookDelivery) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.Url, bytes.NewBufferString(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhookEventHeader, d.Event)
+	req.Header.Set(webhookDeliveryHeader, d.ID.Hex())
+	req.Header.Set(webhookSignatureHeader, "sha256="+signWebhook(w.Secret, d.Payload))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return int64(resp.StatusCode), fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return int64(resp.StatusCode), nil
+}
+
+func signWebhook(secret, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookCacheKey 订阅列表的缓存按应用区分
+func webhookCacheKey(ctx context.Context) string {
+	return identity.FromContext(ctx).AppId
+}
+
+// isValidWebhookUrl 注册时解析域名，解析结果中有内网地址时拒绝
+func (s *WebhookServiceImpl) isValidWebhookUrl(ctx context.Context, rawUrl string) bool {
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	if s.Config.Webhook.AllowPrivateNetwork {
+		return true
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return false
+	}
+	return lo.EveryBy(addrs, func(addr net.IPAddr) bool {
+		return isPublicIP(addr.IP)
+	})
+}
+
+// checkDialAddress 推送时拒绝连接内网地址，避免通过 webhook 访问集群内部服务
+func (s *WebhookServiceImpl) checkDialAddress(_, address string, _ syscall.RawConn) error {
+	if s.Config.Webhook.AllowPrivateNetwork {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", address)
+	}
+	return nil
+}
+
+// isPublicIP 回环、内网、链路本地（包括云厂商的元数据地址 169.254.169.254）、组播以及未指定地址都不是公网地址
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+func isValidWebhookEvents(events []string) bool {
+	return len(events) > 0 && lo.Every([]string{
+		consts.NotificationCreatedHook,
+		consts.NotificationReadHook,
+		consts.NotificationDeletedHook,
+		consts.NotificationRetractedHook,
+	}, events)
+}
+
+var WebhookSet = wire.NewSet(
+	NewWebhookService,
+)
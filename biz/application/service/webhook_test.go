@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+func TestSignWebhook(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		payload string
+		want    string
+	}{
+		{
+			// RFC 4231 test case 2
+			name:    "rfc4231",
+			secret:  "Jefe",
+			payload: "what do ya want for nothing?",
+			want:    "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name:    "empty payload",
+			secret:  "key",
+			payload: "",
+			want:    "5d5d139563c95b5967b9bd9a8c9b233a9dedb45072794cd232dc1b74832607d0",
+		},
+		{
+			name:    "pangram",
+			secret:  "key",
+			payload: "The quick brown fox jumps over the lazy dog",
+			want:    "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signWebhook(tt.secret, tt.payload); got != tt.want {
+				t.Errorf("signWebhook() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidWebhookUrl(t *testing.T) {
+	tests := []struct {
+		url          string
+		allowPrivate bool
+		want         bool
+	}{
+		{url: "https://93.184.216.34/hook", want: true},
+		{url: "http://127.0.0.1:8080"},
+		{url: "http://127.0.0.1:8080", allowPrivate: true, want: true},
+		{url: "http://169.254.169.254/latest/meta-data"},
+		{url: "http://10.0.0.1"},
+		{url: "http://[::1]:8080"},
+		{url: ""},
+		{url: "ftp://example.com"},
+		{url: "https://"},
+		{url: "example.com/hook"},
+	}
+	for _, tt := range tests {
+		s := &WebhookServiceImpl{Config: &config.Config{}}
+		s.Config.Webhook.AllowPrivateNetwork = tt.allowPrivate
+		if got := s.isValidWebhookUrl(context.Background(), tt.url); got != tt.want {
+			t.Errorf("isValidWebhookUrl(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "93.184.216.34", want: true},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{ip: "127.0.0.1"},
+		{ip: "10.1.2.3"},
+		{ip: "172.16.0.1"},
+		{ip: "192.168.1.1"},
+		{ip: "169.254.169.254"},
+		{ip: "0.0.0.0"},
+		{ip: "::1"},
+		{ip: "fe80::1"},
+		{ip: "fd00::1"},
+		{ip: "::ffff:127.0.0.1"},
+	}
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestIsValidWebhookEvents(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []string
+		want   bool
+	}{
+		{name: "empty"},
+		{name: "one", events: []string{consts.NotificationCreatedHook}, want: true},
+		{name: "all", events: []string{consts.NotificationCreatedHook, consts.NotificationReadHook, consts.NotificationDeletedHook, consts.NotificationRetractedHook}, want: true},
+		{name: "unknown", events: []string{consts.NotificationCreatedHook, "notification.unknown"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidWebhookEvents(tt.events); got != tt.want {
+				t.Errorf("isValidWebhookEvents(%v) = %v, want %v", tt.events, got, tt.want)
+			}
+		})
+	}
+}
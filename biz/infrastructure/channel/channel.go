@@ -0,0 +1,45 @@
+package channel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mail"
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
+)
+
+// ErrNoAddress 用户在该渠道没有可用的地址，重试也不会成功
+var ErrNoAddress = errors.New("recipient has no address on this channel")
+
+type (
+	// Channel 站外投递渠道，例如邮件、Web Push、短信
+	Channel interface {
+		Name() string
+		Send(ctx context.Context, msg *Message) error
+	}
+	Message struct {
+		NotificationId string `json:"notificationId"`
+		TargetUserId   string `json:"targetUserId"`
+		Type           int64  `json:"type"`
+		Text           string `json:"text"`
+	}
+	// Channels 按渠道名索引的全部可用渠道
+	Channels map[string]Channel
+)
+
+// NewChannels 邮件复用 Mail 的发送配置，Web Push 和短信尚未接入服务商，暂时由 FakeChannel 代替
+func NewChannels(config *config.Config, sender mail.Sender, preferenceMongoMapper preferencemapper.IPreferenceMongoMapper) (Channels, error) {
+	w, err := openFakeOutput(config.Delivery.FakeOutput)
+	if err != nil {
+		return nil, err
+	}
+	channels := Channels{
+		consts.EmailChannel: NewMailChannel(config.Delivery.MailSubject, sender, preferenceMongoMapper),
+	}
+	for _, name := range []string{consts.PushChannel, consts.SmsChannel} {
+		channels[name] = NewFakeChannel(name, w)
+	}
+	return channels, nil
+}
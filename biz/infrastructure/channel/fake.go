@@ -0,0 +1,59 @@
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+var _ Channel = (*FakeChannel)(nil)
+
+// FakeChannel 将投递内容以 JSON 行写入文件或标准输出，供开发和测试使用
+type FakeChannel struct {
+	name string
+	mu   *sync.Mutex
+	w    io.Writer
+}
+
+var fakeOutputLock sync.Mutex
+
+func NewFakeChannel(name string, w io.Writer) *FakeChannel {
+	return &FakeChannel{
+		name: name,
+		mu:   &fakeOutputLock,
+		w:    w,
+	}
+}
+
+func (c *FakeChannel) Name() string {
+	return c.name
+}
+
+func (c *FakeChannel) Send(_ context.Context, msg *Message) error {
+	line, err := json.Marshal(struct {
+		Channel string    `json:"channel"`
+		SendAt  time.Time `json:"sendAt"`
+		*Message
+	}{
+		Channel: c.name,
+		SendAt:  time.Now(),
+		Message: msg,
+	})
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err = c.w.Write(append(line, '\n'))
+	return err
+}
+
+func openFakeOutput(path string) (io.Writer, error) {
+	if path == "" {
+		return os.Stdout, nil
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+}
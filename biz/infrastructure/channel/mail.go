@@ -0,0 +1,48 @@
+package channel
+
+import (
+	"context"
+	"errors"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mail"
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
+)
+
+var _ Channel = (*MailChannel)(nil)
+
+// MailChannel 通过邮件发送通知，收件地址取自用户的通知偏好
+type MailChannel struct {
+	subject               string
+	sender                mail.Sender
+	preferenceMongoMapper preferencemapper.IPreferenceMongoMapper
+}
+
+func NewMailChannel(subject string, sender mail.Sender, preferenceMongoMapper preferencemapper.IPreferenceMongoMapper) *MailChannel {
+	return &MailChannel{
+		subject:               subject,
+		sender:                sender,
+		preferenceMongoMapper: preferenceMongoMapper,
+	}
+}
+
+func (c *MailChannel) Name() string {
+	return consts.EmailChannel
+}
+
+func (c *MailChannel) Send(ctx context.Context, msg *Message) error {
+	p, err := c.preferenceMongoMapper.GetPreference(ctx, msg.TargetUserId)
+	switch {
+	case errors.Is(err, consts.ErrNotFound) || errors.Is(err, consts.ErrInvalidObjectId):
+		return ErrNoAddress
+	case err != nil:
+		return err
+	case p.Email == "":
+		return ErrNoAddress
+	}
+	return c.sender.Send(ctx, &mail.Mail{
+		To:      p.Email,
+		Subject: c.subject,
+		Text:    msg.Text,
+	})
+}
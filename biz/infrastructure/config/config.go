@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"time"
 
 	"github.com/zeromicro/go-zero/core/conf"
 	"github.com/zeromicro/go-zero/core/service"
@@ -9,7 +10,14 @@ import (
 	"github.com/zeromicro/go-zero/core/stores/redis"
 )
 
+// Route 一种通知类型需要额外投递的站外渠道
+type Route struct {
+	Type     int64
+	Channels []string
+}
+
 type Config struct {
+	// ServiceConf.DevServer 开启后在 /metrics 暴露 Prometheus 指标
 	service.ServiceConf
 	ListenOn string
 	Mongo    struct {
@@ -18,6 +26,207 @@ type Config struct {
 	}
 	CacheConf cache.CacheConf
 	RedisConf redis.RedisConf
+	// Notification 通知相关的业务配置
+	Notification struct {
+		// AckTypes 需要用户显式确认的通知类型
+		AckTypes []int64 `json:",optional"`
+		// Retention 通知的保留时长，超过后未收藏的通知会被清理，为 0 时不清理
+		Retention         time.Duration `json:",default=0s"`
+		RetentionInterval time.Duration `json:",default=1h"`
+		// WakeInterval 检查稍后提醒的通知是否到期的间隔
+		WakeInterval time.Duration `json:",default=10s"`
+		// TombstoneRetention 删除记录的保留时长，客户端超过该时长未同步时需要全量拉取
+		TombstoneRetention time.Duration `json:",default=720h"`
+		// SyncMaxItems 单次增量同步最多返回的变更数，超过时要求客户端全量拉取
+		SyncMaxItems int64 `json:",default=500"`
+		// MaxLabelLength 自定义标签的最大长度（字符数）
+		MaxLabelLength int `json:",default=32"`
+		// MaxLabels 单条通知最多的自定义标签数
+		MaxLabels int64 `json:",default=20"`
+	}
+	// Delivery 站外推送（邮件、Web Push、短信）相关配置
+	Delivery struct {
+		// Routes 每种通知类型需要额外投递的渠道
+		Routes      []Route       `json:",optional"`
+		MaxAttempts int64         `json:",default=5"`
+		Backoff     time.Duration `json:",default=1s"`
+		MaxBackoff  time.Duration `json:",default=10m"`
+		Interval    time.Duration `json:",default=1s"`
+		// Lease 领取投递记录后的租期，发送进程崩溃时记录在租期结束后可以被重新领取
+		Lease time.Duration `json:",default=1m"`
+		// MailSubject 邮件渠道的邮件标题
+		MailSubject string `json:",default=CloudMind通知"`
+		// FakeOutput 本地假渠道的输出文件，为空时输出到标准输出
+		FakeOutput string `json:",optional"`
+	}
+	// Mail 邮件发送配置，Driver 为 file 时只把邮件写入 DropDir
+	Mail struct {
+		Driver string `json:",default=file,options=smtp|file"`
+		From   string `json:",optional"`
+		Smtp   struct {
+			Host     string `json:",optional"`
+			Port     int    `json:",default=25"`
+			Username string `json:",optional"`
+			Password string `json:",optional"`
+		}
+		DropDir string `json:",default=mail"`
+	}
+	// RateLimit 通知防刷配置，Limit 为 0 表示不限制
+	RateLimit struct {
+		// PairLimit 同一来源用户对同一目标用户同一类型通知在 PairWindow 内的上限
+		PairLimit  int           `json:",default=0"`
+		PairWindow time.Duration `json:",default=1h"`
+		// SourceLimit 同一来源用户在 SourceWindow 内产生通知的上限
+		SourceLimit  int           `json:",default=0"`
+		SourceWindow time.Duration `json:",default=1h"`
+		// Action 超限后的处理方式，drop 直接丢弃，merge 合并到最近一条同类通知
+		Action string `json:",default=drop,options=drop|merge"`
+	}
+	// Sensitive 敏感词过滤配置
+	Sensitive struct {
+		Words []string `json:",optional"`
+		// WordFile 词表文件，每行一个词，修改后按 ReloadInterval 自动重新加载
+		WordFile       string        `json:",optional"`
+		ReloadInterval time.Duration `json:",default=30s"`
+		// Mode 命中后的处理方式：reject 拒绝，mask 打码，review 原样保存并标记待审核
+		Mode string `json:",default=mask,options=reject|mask|review"`
+		Mask string `json:",default=*"`
+	}
+	// EventQueue 上游领域事件的消费配置
+	EventQueue struct {
+		Driver string `json:",default=memory,options=memory|kafka"`
+		Buffer int    `json:",default=1024"`
+		Kafka  struct {
+			Brokers   []string `json:",optional"`
+			Group     string   `json:",optional"`
+			Topic     string   `json:",optional"`
+			Consumers int      `json:",default=8"`
+			// Backoff 事件处理失败后的首次重试间隔，之后每次翻倍，最多为 MaxBackoff
+			Backoff    time.Duration `json:",default=1s"`
+			MaxBackoff time.Duration `json:",default=1m"`
+		}
+		// Rules 事件到通知的映射规则，一个事件可以命中多条规则
+		Rules []struct {
+			Event      string
+			Type       int64
+			TargetType int64 `json:",optional"`
+			// Text 通知文案，text/template 语法，可以引用事件字段，例如 {{.Extra.title}}
+			Text string `json:",optional"`
+			// Broadcast 为 true 时发送给全站用户
+			Broadcast bool `json:",optional"`
+		} `json:",optional"`
+	}
+	// Webhook 通知生命周期事件的对外推送配置
+	Webhook struct {
+		Timeout     time.Duration `json:",default=5s"`
+		MaxAttempts int64         `json:",default=8"`
+		Backoff     time.Duration `json:",default=5s"`
+		MaxBackoff  time.Duration `json:",default=1h"`
+		Interval    time.Duration `json:",default=1s"`
+		// Lease 领取推送记录后的租期，需要大于 Timeout
+		Lease time.Duration `json:",default=1m"`
+		// CacheTTL 各应用订阅列表在内存中的缓存时长
+		CacheTTL time.Duration `json:",default=10s"`
+		// AllowPrivateNetwork 为 true 时允许推送到回环、内网地址，只应在本地调试时开启
+		AllowPrivateNetwork bool `json:",optional"`
+	}
+	// Digest 未读通知邮件摘要任务配置
+	Digest struct {
+		Interval time.Duration `json:",default=1h"`
+		MaxItems int64         `json:",default=50"`
+		Subject  string        `json:",default=CloudMind未读通知摘要"`
+		// Lease 领取摘要后的租期，发送失败且未能释放时租期结束后可以被重新领取
+		Lease time.Duration `json:",default=10m"`
+		// TypeNames 摘要中各通知类型分组的标题
+		TypeNames []struct {
+			Type int64
+			Name string
+		} `json:",optional"`
+	}
+	// Analytics 通知互动统计任务配置
+	Analytics struct {
+		Interval time.Duration `json:",default=1h"`
+		// TimeZone 按该时区划分统计日
+		TimeZone string `json:",default=Asia/Shanghai"`
+		// Days 每次重新汇总最近几天的数据，互动计入通知创建当天，早于这个范围的互动不再计入
+		Days int `json:",default=7"`
+		// OpenBuffer 等待写入的打开记录批数，写入跟不上时丢弃新的记录
+		OpenBuffer int `json:",default=1024"`
+	}
+	// Auth 接口鉴权配置
+	Auth struct {
+		// Enabled 为 false 时不做鉴权，只应在本地调试时关闭
+		Enabled bool `json:",default=true"`
+		// Callers 各上游服务签发调用方令牌的密钥，没有有效令牌的调用视为未知调用方
+		Callers []struct {
+			Name   string
+			Secret string
+		} `json:",optional"`
+		// Policies 各接口允许的上游服务和用户角色，* 表示不限
+		Policies []struct {
+			Method  string
+			Callers []string `json:",optional"`
+			Roles   []string `json:",optional"`
+		} `json:",optional"`
+		// DefaultCallers 没有配置策略的接口允许的上游服务
+		DefaultCallers []string `json:",optional"`
+		// AdminRoles 具有这些角色的用户可以读写其他用户的通知
+		AdminRoles []string `json:",optional"`
+	}
+	// Admin 内部管理 HTTP 服务，提供存活和就绪探针、构建信息、当前配置以及 pprof
+	Admin struct {
+		// ListenOn 默认只监听本机，需要被集群内的探针访问时显式配置为 Pod 地址
+		ListenOn string `json:",default=127.0.0.1:8090"`
+		// PingTimeout 就绪检查中 ping Mongo、Redis 的超时时间
+		PingTimeout time.Duration `json:",default=2s"`
+	}
+	// Shutdown 优雅退出配置
+	Shutdown struct {
+		// RPCTimeout 停止接收新请求后等待进行中的 RPC 完成的时长
+		RPCTimeout time.Duration `json:",default=5s"`
+		// Timeout 等待后台任务停止以及关闭存储的时长
+		Timeout time.Duration `json:",default=30s"`
+	}
+	// Overload 接口限流与自适应降载配置
+	Overload struct {
+		// CpuThreshold 触发自适应降载的 CPU 使用率（千分比），为 0 时不降载
+		CpuThreshold int64 `json:",default=900"`
+		// Limits 各接口的令牌桶限流，Rate 为每秒放入的令牌数，Burst 为桶容量，为 0 时等于 Rate；
+		// UserRate 不为 0 时每个终端用户另有一个独立的令牌桶
+		Limits []struct {
+			Method    string
+			Rate      int `json:",optional"`
+			Burst     int `json:",optional"`
+			UserRate  int `json:",optional"`
+			UserBurst int `json:",optional"`
+		} `json:",optional"`
+	}
+	// Tenants 各应用覆盖的通知配置，未配置的应用以及未覆盖的项沿用全局配置
+	Tenants []struct {
+		AppId    string
+		AckTypes []int64 `json:",optional"`
+		Routes   []Route `json:",optional"`
+	} `json:",optional"`
+}
+
+// AckTypesOf 返回应用需要用户显式确认的通知类型
+func (c *Config) AckTypesOf(appId string) []int64 {
+	for _, t := range c.Tenants {
+		if t.AppId == appId && t.AckTypes != nil {
+			return t.AckTypes
+		}
+	}
+	return c.Notification.AckTypes
+}
+
+// RoutesOf 返回应用的站外投递路由
+func (c *Config) RoutesOf(appId string) []Route {
+	for _, t := range c.Tenants {
+		if t.AppId == appId && t.Routes != nil {
+			return t.Routes
+		}
+	}
+	return c.Delivery.Routes
 }
 
 func NewConfig() (*Config, error) {
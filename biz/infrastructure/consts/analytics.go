@@ -0,0 +1,10 @@
+package consts
+
+// 用户对通知的互动事件
+const (
+	OpenEvent  = "open"
+	ClickEvent = "click"
+)
+
+// StatDateLayout 通知统计按天汇总时的日期格式
+const StatDateLayout = "2006-01-02"
@@ -0,0 +1,13 @@
+package consts
+
+const (
+	EmailChannel = "email"
+	PushChannel  = "push"
+	SmsChannel   = "sms"
+)
+
+const (
+	DeliveryStatusPending int64 = iota + 1
+	DeliveryStatusSuccess
+	DeliveryStatusFailed
+)
@@ -7,4 +7,10 @@ import (
 var (
 	ErrNotFound        = status.Error(10001, "no such element")
 	ErrInvalidObjectId = status.Error(10002, "invalid objectId")
+	ErrInvalidArgument = status.Error(10003, "invalid argument")
+	ErrSensitiveText   = status.Error(10004, "text contains sensitive words")
+	// ErrPermissionDenied 调用方无权访问该接口或该用户的数据
+	ErrPermissionDenied = status.Error(10005, "permission denied")
+	// ErrOverloaded 触发限流或降载，网关应映射为 429
+	ErrOverloaded = status.Error(10006, "too many requests")
 )
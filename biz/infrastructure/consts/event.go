@@ -0,0 +1,22 @@
+package consts
+
+const (
+	MemoryQueueDriver = "memory"
+	KafkaQueueDriver  = "kafka"
+)
+
+// 上游服务发布的领域事件类型
+const (
+	LikeEvent             = "like"
+	CommentEvent          = "comment"
+	FollowEvent           = "follow"
+	ContentPublishedEvent = "content_published"
+)
+
+// 对外推送的通知生命周期事件
+const (
+	NotificationCreatedHook   = "notification.created"
+	NotificationReadHook      = "notification.read"
+	NotificationDeletedHook   = "notification.deleted"
+	NotificationRetractedHook = "notification.retracted"
+)
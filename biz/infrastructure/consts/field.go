@@ -11,8 +11,46 @@ const (
 	ImageUrl              = "imageUrl"
 	Sum                   = "sum"
 	Read                  = "read"
+	ReadAt                = "readAt"
 	IsPublic              = "isPublic"
 	Status                = "status"
 	NotificationSystemKey = "system"
+	NeedAck               = "needAck"
+	NotificationId        = "notificationId"
+	UserId                = "userId"
+	NextAttemptAt         = "nextAttemptAt"
+	Email                 = "email"
+	DigestFrequency       = "digestFrequency"
+	LastDigestAt          = "lastDigestAt"
+	DigestLeaseUntil      = "digestLeaseUntil"
+	TimeZone              = "timeZone"
+	QuietStart            = "quietStart"
+	QuietEnd              = "quietEnd"
+	SourceUserId          = "sourceUserId"
+	MergeCount            = "mergeCount"
+	Events                = "events"
+	WebhookId             = "webhookId"
+	SourceContentId       = "sourceContentId"
+	Text                  = "text"
+	Payload               = "payload"
+	NeedReview            = "needReview"
+	IsStarred             = "isStarred"
+	SnoozeUntil           = "snoozeUntil"
+	WokeAt                = "wokeAt"
+	SortAt                = "sortAt"
+	IsArchived            = "isArchived"
+	Labels                = "labels"
+	Grams                 = "grams"
+	BlockedUserId         = "blockedUserId"
+	IsHidden              = "isHidden"
+	Event                 = "event"
+	Date                  = "date"
+	IsRetracted           = "isRetracted"
+	Method                = "method"
+	Actor                 = "actor"
+	TargetIds             = "targetIds"
+	AppId                 = "appId"
+	NotificationCreateAt  = "notificationCreateAt"
+	Broadcast             = "broadcast"
 	//NotificationAll          = "all"
 )
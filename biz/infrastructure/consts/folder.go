@@ -0,0 +1,7 @@
+package consts
+
+// 通知的内置文件夹，其余文件夹名为用户自定义标签
+const (
+	InboxFolder    = "inbox"
+	ArchivedFolder = "archived"
+)
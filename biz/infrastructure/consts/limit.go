@@ -0,0 +1,6 @@
+package consts
+
+const (
+	RateLimitDrop  = "drop"
+	RateLimitMerge = "merge"
+)
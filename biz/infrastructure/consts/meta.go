@@ -0,0 +1,10 @@
+package consts
+
+// 上游通过 metainfo 透传的调用信息
+const (
+	UserIdMetaKey = "userId"
+	RoleMetaKey   = "role"
+	AppIdMetaKey  = "appId"
+	// CallerTokenMetaKey 上游服务签发的调用方令牌，见 identity.Sign
+	CallerTokenMetaKey = "callerToken"
+)
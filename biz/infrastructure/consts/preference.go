@@ -0,0 +1,14 @@
+package consts
+
+const (
+	DigestFrequencyDaily int64 = iota + 1
+	DigestFrequencyWeekly
+)
+
+const (
+	SmtpMailDriver = "smtp"
+	FileMailDriver = "file"
+)
+
+// QuietHoursLayout 免打扰时段的时间格式
+const QuietHoursLayout = "15:04"
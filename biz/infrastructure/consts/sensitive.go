@@ -0,0 +1,7 @@
+package consts
+
+const (
+	SensitiveReject = "reject"
+	SensitiveMask   = "mask"
+	SensitiveReview = "review"
+)
@@ -0,0 +1,79 @@
+package identity
+
+import (
+	"context"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+// Identity 一次调用的发起方，Caller 为上游服务名，UserId、Role 为上游透传的终端用户及其角色，
+// AppId 为调用所属的应用，为空时属于默认应用
+type Identity struct {
+	Caller string
+	UserId string
+	Role   string
+	AppId  string
+	// allApps 为 true 时不按应用隔离，只用于需要处理全部应用数据的后台任务
+	allApps bool
+}
+
+type identityKey struct{}
+
+// SystemContext 后台任务使用的上下文，读写不限定应用
+func SystemContext() context.Context {
+	return WithIdentity(context.Background(), &Identity{allApps: true})
+}
+
+// AppContext 后台任务按应用处理数据时使用的上下文，读写只限定在 appId 对应的应用，取消时随 ctx 一同取消
+func AppContext(ctx context.Context, appId string) context.Context {
+	return WithIdentity(ctx, &Identity{AppId: appId})
+}
+
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext 取出调用方身份，没有经过鉴权中间件时返回空身份
+func FromContext(ctx context.Context) *Identity {
+	if id, ok := ctx.Value(identityKey{}).(*Identity); ok {
+		return id
+	}
+	return &Identity{}
+}
+
+// HasRole 判断身份是否具有 roles 中的任一角色
+func (i *Identity) HasRole(roles []string) bool {
+	return i.Role != "" && lo.Contains(roles, i.Role)
+}
+
+// AppFilter 返回按应用隔离时 appId 字段应当匹配的值，默认应用的数据没有 appId 字段，匹配 null；
+// ok 为 false 时不需要隔离
+func AppFilter(ctx context.Context) (value any, ok bool) {
+	id := FromContext(ctx)
+	if id.allApps {
+		return nil, false
+	}
+	if id.AppId == "" {
+		return nil, true
+	}
+	return id.AppId, true
+}
+
+// Scope 在查询条件中加入应用隔离，返回传入的 filter。upsert 时 appId 会随查询条件写入新记录
+func Scope(ctx context.Context, filter bson.M) bson.M {
+	if appId, ok := AppFilter(ctx); ok {
+		filter[consts.AppId] = appId
+	}
+	return filter
+}
+
+// CacheKeyPrefix 缓存键中区分应用的部分，默认应用沿用原来的缓存键
+func CacheKeyPrefix(ctx context.Context) string {
+	if appId := FromContext(ctx).AppId; appId != "" {
+		return appId + ":"
+	}
+	return ""
+}
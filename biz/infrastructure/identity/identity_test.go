@@ -0,0 +1,38 @@
+package identity
+
+import (
+	"context"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+func TestScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		wantScope bool
+		wantAppId any
+	}{
+		{name: "no identity", ctx: context.Background(), wantScope: true, wantAppId: nil},
+		{name: "default app", ctx: AppContext(context.Background(), ""), wantScope: true, wantAppId: nil},
+		{name: "app", ctx: AppContext(context.Background(), "a"), wantScope: true, wantAppId: "a"},
+		{name: "caller", ctx: WithIdentity(context.Background(), &Identity{Caller: "c", AppId: "b"}), wantScope: true, wantAppId: "b"},
+		{name: "system", ctx: SystemContext(), wantScope: false},
+		{name: "app overrides system", ctx: AppContext(SystemContext(), "a"), wantScope: true, wantAppId: "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := Scope(tt.ctx, bson.M{consts.UserId: "u"})
+			appId, ok := filter[consts.AppId]
+			if ok != tt.wantScope || appId != tt.wantAppId {
+				t.Fatalf("appId = %v, %v, want %v, %v", appId, ok, tt.wantAppId, tt.wantScope)
+			}
+			if filter[consts.UserId] != "u" {
+				t.Fatalf("userId = %v, want u", filter[consts.UserId])
+			}
+		})
+	}
+}
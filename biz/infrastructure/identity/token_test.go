@@ -0,0 +1,40 @@
+package identity
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secrets := map[string]string{"cloudmind.content": "s3cret", "empty": ""}
+	id := &Identity{Caller: "cloudmind.content", UserId: "u1", Role: "user", AppId: "app"}
+	valid := Sign("s3cret", id, now.Add(time.Minute))
+
+	tests := []struct {
+		name       string
+		token      string
+		id         *Identity
+		wantCaller string
+		wantOk     bool
+	}{
+		{name: "valid", token: valid, id: id, wantCaller: "cloudmind.content", wantOk: true},
+		{name: "empty", token: "", id: id},
+		{name: "malformed", token: "cloudmind", id: id},
+		{name: "unknown caller", token: Sign("s3cret", &Identity{Caller: "other"}, now.Add(time.Minute)), id: &Identity{}},
+		{name: "empty secret", token: Sign("", &Identity{Caller: "empty"}, now.Add(time.Minute)), id: &Identity{}},
+		{name: "wrong secret", token: Sign("guess", id, now.Add(time.Minute)), id: id},
+		{name: "expired", token: Sign("s3cret", id, now.Add(-time.Second)), id: id},
+		{name: "tampered role", token: valid, id: &Identity{UserId: "u1", Role: "admin", AppId: "app"}},
+		{name: "tampered user", token: valid, id: &Identity{UserId: "u2", Role: "user", AppId: "app"}},
+		{name: "tampered app", token: valid, id: &Identity{UserId: "u1", Role: "user", AppId: "other"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caller, ok := Verify(secrets, tt.token, tt.id, now)
+			if caller != tt.wantCaller || ok != tt.wantOk {
+				t.Fatalf("Verify() = %q, %v, want %q, %v", caller, ok, tt.wantCaller, tt.wantOk)
+			}
+		})
+	}
+}
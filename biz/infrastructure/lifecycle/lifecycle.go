@@ -0,0 +1,113 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/zeromicro/go-zero/core/proc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+)
+
+// forceQuitMargin go-zero 收到退出信号后会在一段时间后强制结束进程，需要比优雅退出的总时长多留一些余量
+const forceQuitMargin = 5 * time.Second
+
+// Hook 组件的启动和停止回调，OnStop 应当在 ctx 到期前返回，两者都可以为空
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle 统一管理组件的启停。组件在构造函数中注册回调，启动时按注册顺序执行，
+// 停止时按相反顺序执行，存储类组件在其他组件全部停止后才关闭
+type Lifecycle struct {
+	mu      sync.Mutex
+	hooks   []Hook
+	stores  []Hook
+	started int
+}
+
+func NewLifecycle(config *config.Config) *Lifecycle {
+	proc.SetTimeToForceQuit(config.Shutdown.RPCTimeout + config.Shutdown.Timeout + forceQuitMargin)
+	return &Lifecycle{}
+}
+
+func (l *Lifecycle) Append(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.hooks = append(l.hooks, hook)
+}
+
+// AppendStore 注册 Mongo 等存储的关闭回调，它们会在所有组件停止之后执行
+func (l *Lifecycle) AppendStore(hook Hook) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stores = append(l.stores, hook)
+}
+
+// Start 依次启动组件，某个组件启动失败时停止已经启动的组件并返回错误
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, hook := range l.hooks {
+		if hook.OnStart != nil {
+			if err := hook.OnStart(ctx); err != nil {
+				stopErr := l.stop(ctx)
+				return errors.Join(fmt.Errorf("start %s: %w", hook.Name, err), stopErr)
+			}
+		}
+		l.started++
+	}
+	return nil
+}
+
+// Stop 逆序停止已启动的组件，再关闭存储，返回过程中的全部错误
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stop(ctx)
+}
+
+func (l *Lifecycle) stop(ctx context.Context) error {
+	var errs []error
+	run := func(hooks []Hook) {
+		for i := len(hooks) - 1; i >= 0; i-- {
+			hook := hooks[i]
+			if hook.OnStop == nil {
+				continue
+			}
+			start := time.Now()
+			if err := hook.OnStop(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("stop %s: %w", hook.Name, err))
+				continue
+			}
+			log.Info("[Lifecycle] stopped %s in %v", hook.Name, time.Since(start))
+		}
+	}
+	run(l.hooks[:l.started])
+	l.started = 0
+	run(l.stores)
+	l.stores = nil
+	return errors.Join(errs...)
+}
+
+// ExitSignal 收到 SIGTERM 或 SIGINT 时返回，作为 Kitex 的退出信号，Kitex 随后停止接收新请求并等待进行中的请求完成
+func (l *Lifecycle) ExitSignal() <-chan error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	errCh := make(chan error, 1)
+	go func() {
+		sig := <-signals
+		log.Info("[Lifecycle] received %v, shutting down", sig)
+		errCh <- nil
+	}()
+	return errCh
+}
@@ -0,0 +1,65 @@
+package lifecycle
+
+import (
+	"context"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/threading"
+)
+
+// Ticker 每隔 interval 以 ctx 运行一次 run 的后台任务，停止时取消传给 run 的 ctx 并不再开始新的一轮，
+// 等待正在运行的一轮结束，run 中的循环应在 ctx 取消后尽快返回
+func Ticker(ctx context.Context, name string, interval time.Duration, run func(ctx context.Context)) Hook {
+	return Worker(name, func(stop <-chan struct{}) {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		threading.GoSafe(func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		})
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				run(ctx)
+			}
+		}
+	})
+}
+
+// Worker 在独立的 goroutine 中运行 loop，loop 应当在 stop 关闭后尽快返回，停止时等待 loop 返回
+func Worker(name string, loop func(stop <-chan struct{})) Hook {
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	return Hook{
+		Name: name,
+		OnStart: func(context.Context) error {
+			threading.GoSafe(func() {
+				defer close(done)
+				loop(stop)
+			})
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			close(stop)
+			// 前面的组件用完了 ctx 的时间时，已经结束的 loop 不应报告超时
+			select {
+			case <-done:
+				return nil
+			default:
+			}
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
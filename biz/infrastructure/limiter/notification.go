@@ -0,0 +1,78 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+)
+
+const (
+	pairKeyPrefix   = "limit:notification:pair:"
+	sourceKeyPrefix = "limit:notification:source:"
+)
+
+// slidingWindowScript 基于有序集合的滑动窗口，所有窗口都未超限时才记录本次请求
+// KEYS[i] 窗口 key，所有 key 都带有来源用户的 hash tag 以落在 Redis Cluster 的同一个 slot，ARGV: now, member, 之后每两个参数依次为对应 key 的 window 与 limit（毫秒/次数）
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+for i, key in ipairs(KEYS) do
+	local window = tonumber(ARGV[i * 2 + 1])
+	local limit = tonumber(ARGV[i * 2 + 2])
+	redis.call("ZREMRANGEBYSCORE", key, 0, now - window)
+	if redis.call("ZCARD", key) >= limit then
+		return 0
+	end
+end
+for i, key in ipairs(KEYS) do
+	local window = tonumber(ARGV[i * 2 + 1])
+	redis.call("ZADD", key, now, ARGV[2])
+	redis.call("PEXPIRE", key, window)
+end
+return 1
+`)
+
+type INotificationLimiter interface {
+	Allow(ctx context.Context, sourceUserId, targetUserId string, typ int64) (bool, error)
+}
+
+type NotificationLimiter struct {
+	config *config.Config
+	redis  *redis.Redis
+}
+
+func NewNotificationLimiter(config *config.Config, redis *redis.Redis) INotificationLimiter {
+	return &NotificationLimiter{
+		config: config,
+		redis:  redis,
+	}
+}
+
+// Allow 判断来源用户本次产生的通知是否在频率限制之内
+func (l *NotificationLimiter) Allow(ctx context.Context, sourceUserId, targetUserId string, typ int64) (bool, error) {
+	c := l.config.RateLimit
+	now := time.Now().UnixMilli()
+	keys := make([]string, 0, 2)
+	args := []any{now, primitive.NewObjectID().Hex()}
+	if c.PairLimit > 0 {
+		keys = append(keys, fmt.Sprintf("%s{%s}:%s:%d", pairKeyPrefix, sourceUserId, targetUserId, typ))
+		args = append(args, c.PairWindow.Milliseconds(), c.PairLimit)
+	}
+	if c.SourceLimit > 0 {
+		keys = append(keys, fmt.Sprintf("%s{%s}", sourceKeyPrefix, sourceUserId))
+		args = append(args, c.SourceWindow.Milliseconds(), c.SourceLimit)
+	}
+	if len(keys) == 0 {
+		return true, nil
+	}
+	res, err := l.redis.ScriptRunCtx(ctx, slidingWindowScript, keys, args...)
+	if err != nil {
+		return false, err
+	}
+	allowed, ok := res.(int64)
+	return ok && allowed == 1, nil
+}
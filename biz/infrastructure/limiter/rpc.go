@@ -0,0 +1,98 @@
+package limiter
+
+import (
+	"context"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/zeromicro/go-zero/core/limit"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+)
+
+const rpcKeyPrefix = "limit:rpc:"
+
+// tokenBucketScript 所有用户共用的令牌桶，每个用户的令牌数与上次取令牌的时间存放在 KEYS[1] 对应的 hash 中，
+// 桶装满后 key 过期，ARGV: rate, burst, now（次/秒、个、毫秒）
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate / 1000)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tokens, "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst * 1000 / rate) + 1000)
+return allowed
+`)
+
+type IRPCLimiter interface {
+	Allow(ctx context.Context, method string, userId string) bool
+}
+
+type rpcLimit struct {
+	method    *limit.TokenLimiter
+	userRate  int
+	userBurst int
+}
+
+// RPCLimiter 按接口和终端用户限流的令牌桶，令牌存放在 redis 中以便多个实例共享。
+// 接口限流在 redis 不可用时退化为单机限流，用户限流在 redis 不可用时放行
+type RPCLimiter struct {
+	redis  *redis.Redis
+	limits map[string]*rpcLimit
+}
+
+func NewRPCLimiter(config *config.Config, redis *redis.Redis) IRPCLimiter {
+	l := &RPCLimiter{
+		redis:  redis,
+		limits: map[string]*rpcLimit{},
+	}
+	for _, c := range config.Overload.Limits {
+		rl := &rpcLimit{
+			userRate:  c.UserRate,
+			userBurst: orRate(c.UserBurst, c.UserRate),
+		}
+		if c.Rate > 0 {
+			rl.method = limit.NewTokenLimiter(c.Rate, orRate(c.Burst, c.Rate), redis, rpcKeyPrefix+c.Method)
+		}
+		l.limits[c.Method] = rl
+	}
+	return l
+}
+
+// Allow 判断本次调用是否在接口和用户的限流之内，没有透传终端用户的调用只受接口限流
+func (l *RPCLimiter) Allow(ctx context.Context, method string, userId string) bool {
+	rl, ok := l.limits[method]
+	if !ok {
+		return true
+	}
+	if rl.method != nil && !rl.method.AllowCtx(ctx) {
+		return false
+	}
+	if rl.userRate <= 0 || userId == "" {
+		return true
+	}
+	res, err := l.redis.ScriptRunCtx(ctx, tokenBucketScript, []string{rpcKeyPrefix + method + ":" + userId},
+		rl.userRate, rl.userBurst, time.Now().UnixMilli())
+	if err != nil {
+		log.CtxError(ctx, "[Limiter] take token of %s for user %s failed, err=%v", method, userId, err)
+		return true
+	}
+	allowed, ok := res.(int64)
+	return ok && allowed == 1
+}
+
+func orRate(burst, rate int) int {
+	if burst > 0 {
+		return burst
+	}
+	return rate
+}
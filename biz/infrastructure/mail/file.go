@@ -0,0 +1,38 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+)
+
+var _ Sender = (*FileSender)(nil)
+
+// FileSender 把邮件以 .eml 文件写入本地目录，用于开发环境
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(config *config.Config) *FileSender {
+	return &FileSender{
+		dir:  config.Mail.DropDir,
+		from: config.Mail.From,
+	}
+}
+
+func (s *FileSender) Send(_ context.Context, mail *Mail) error {
+	msg, err := buildMessage(s.from, mail)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), mail.To)
+	return os.WriteFile(filepath.Join(s.dir, filepath.Base(name)), msg, 0o644)
+}
@@ -0,0 +1,29 @@
+package mail
+
+import (
+	"context"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+type (
+	Sender interface {
+		Send(ctx context.Context, mail *Mail) error
+	}
+	Mail struct {
+		To      string
+		Subject string
+		Text    string
+		Html    string
+	}
+)
+
+func NewSender(config *config.Config) Sender {
+	switch config.Mail.Driver {
+	case consts.SmtpMailDriver:
+		return NewSmtpSender(config)
+	default:
+		return NewFileSender(config)
+	}
+}
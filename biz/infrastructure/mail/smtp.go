@@ -0,0 +1,81 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+)
+
+var _ Sender = (*SmtpSender)(nil)
+
+type SmtpSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSmtpSender(config *config.Config) *SmtpSender {
+	c := config.Mail.Smtp
+	s := &SmtpSender{
+		addr: net.JoinHostPort(c.Host, strconv.Itoa(c.Port)),
+		from: config.Mail.From,
+	}
+	if c.Username != "" {
+		s.auth = smtp.PlainAuth("", c.Username, c.Password, c.Host)
+	}
+	return s
+}
+
+func (s *SmtpSender) Send(_ context.Context, mail *Mail) error {
+	msg, err := buildMessage(s.from, mail)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{mail.To}, msg)
+}
+
+// buildMessage 生成同时包含纯文本与 HTML 两种正文的 MIME 邮件
+func buildMessage(from string, mail *Mail) ([]byte, error) {
+	if strings.ContainsAny(mail.To, "\r\n") {
+		return nil, errors.New("mail: invalid recipient address")
+	}
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", mail.Text},
+		{"text/html; charset=UTF-8", mail.Html},
+	} {
+		pw, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = pw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", mail.To)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", mail.Subject))
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", w.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
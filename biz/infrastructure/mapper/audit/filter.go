@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+type FilterOptions struct {
+	OnlyMethod         *string
+	OnlyActor          *string
+	OnlyTargetId       *string
+	OnlyCreateAtAfter  *time.Time
+	OnlyCreateAtBefore *time.Time
+}
+
+type MongoFilter struct {
+	m bson.M
+	*FilterOptions
+}
+
+func MakeBsonFilter(options *FilterOptions) bson.M {
+	return (&MongoFilter{
+		m:             bson.M{},
+		FilterOptions: options,
+	}).toBson()
+}
+
+func (f *MongoFilter) toBson() bson.M {
+	f.CheckOnlyMethod()
+	f.CheckOnlyActor()
+	f.CheckOnlyTargetId()
+	f.CheckOnlyCreateAt()
+	return f.m
+}
+
+func (f *MongoFilter) CheckOnlyMethod() {
+	if f.OnlyMethod != nil {
+		f.m[consts.Method] = *f.OnlyMethod
+	}
+}
+
+func (f *MongoFilter) CheckOnlyActor() {
+	if f.OnlyActor != nil {
+		f.m[consts.Actor] = *f.OnlyActor
+	}
+}
+
+func (f *MongoFilter) CheckOnlyTargetId() {
+	if f.OnlyTargetId != nil {
+		f.m[consts.TargetIds] = *f.OnlyTargetId
+	}
+}
+
+func (f *MongoFilter) CheckOnlyCreateAt() {
+	m := bson.M{}
+	if f.OnlyCreateAtAfter != nil {
+		m["$gte"] = *f.OnlyCreateAtAfter
+	}
+	if f.OnlyCreateAtBefore != nil {
+		m["$lt"] = *f.OnlyCreateAtBefore
+	}
+	if len(m) > 0 {
+		f.m[consts.CreateAt] = m
+	}
+}
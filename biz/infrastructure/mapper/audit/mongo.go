@@ -0,0 +1,111 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination"
+	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"github.com/samber/lo"
+	"github.com/zeromicro/go-zero/core/mr"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName = "auditLog"
+)
+
+var _ IAuditMongoMapper = (*MongoMapper)(nil)
+
+type (
+	// IAuditMongoMapper 审计日志只允许追加和查询，不提供修改和删除
+	IAuditMongoMapper interface {
+		InsertOne(ctx context.Context, data *AuditLog) error
+		GetAuditLogsAndCount(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*AuditLog, int64, error)
+	}
+	AuditLog struct {
+		ID primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		// Caller 发起调用的上游服务
+		Caller string `bson:"caller,omitempty" json:"caller,omitempty"`
+		// Actor 上游传递的操作用户
+		Actor     string   `bson:"actor,omitempty" json:"actor,omitempty"`
+		Method    string   `bson:"method,omitempty" json:"method,omitempty"`
+		TargetIds []string `bson:"targetIds,omitempty" json:"targetIds,omitempty"`
+		// Before、After 调用前后目标对象的 JSON 快照
+		Before   string    `bson:"before,omitempty" json:"before,omitempty"`
+		After    string    `bson:"after,omitempty" json:"after,omitempty"`
+		Error    string    `bson:"error,omitempty" json:"error,omitempty"`
+		AppId    string    `bson:"appId,omitempty" json:"appId,omitempty"`
+		CreateAt time.Time `bson:"createAt,omitempty" json:"createAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+func (m *MongoMapper) InsertOne(ctx context.Context, data *AuditLog) error {
+	defer metrics.ObserveMongo(CollectionName, "InsertOne", time.Now())
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+	}
+	data.CreateAt = time.Now()
+	data.AppId = identity.FromContext(ctx).AppId
+	_, err := m.conn.InsertOneNoCache(ctx, data)
+	return err
+}
+
+func (m *MongoMapper) GetAuditLogsAndCount(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*AuditLog, int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetAuditLogsAndCount", time.Now())
+	var (
+		data       []*AuditLog
+		count      int64
+		err1, err2 error
+	)
+	p := mongop.NewMongoPaginator(pagination.NewRawStore(sorter), popts)
+
+	filter := identity.Scope(ctx, MakeBsonFilter(fopts))
+	countFilter := identity.Scope(ctx, MakeBsonFilter(fopts))
+	sort, err := p.MakeSortOptions(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if err = mr.Finish(func() error {
+		count, err1 = m.conn.CountDocuments(ctx, countFilter)
+		return err1
+	}, func() error {
+		if err2 = m.conn.Find(ctx, &data, filter, &options.FindOptions{
+			Sort:  sort,
+			Limit: popts.Limit,
+			Skip:  popts.Offset,
+		}); err2 != nil {
+			return err2
+		}
+		// 如果是反向查询，反转数据
+		if *popts.Backward {
+			lo.Reverse(data)
+		}
+		if len(data) > 0 {
+			return p.StoreCursor(ctx, data[0], data[len(data)-1])
+		}
+		return nil
+	}); err != nil {
+		return nil, 0, err
+	}
+
+	return data, count, nil
+}
+
+func NewAuditModel(config *config.Config) IAuditMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	return &MongoMapper{
+		conn: conn,
+	}
+}
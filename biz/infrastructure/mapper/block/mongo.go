@@ -0,0 +1,107 @@
+package block
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName = "block"
+	BlockKeyPrefix = "cache:block:"
+)
+
+var _ IBlockMongoMapper = (*MongoMapper)(nil)
+
+type (
+	// IBlockMongoMapper 用户之间的屏蔽关系，被屏蔽用户触发的通知不会发给屏蔽者
+	IBlockMongoMapper interface {
+		Block(ctx context.Context, userId string, blockedUserId string) error
+		Unblock(ctx context.Context, userId string, blockedUserId string) error
+		IsBlocked(ctx context.Context, userId string, blockedUserId string) (bool, error)
+		GetBlockedUserIds(ctx context.Context, userId string) ([]string, error)
+	}
+	Block struct {
+		ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		UserId        string             `bson:"userId,omitempty" json:"userId,omitempty"`
+		BlockedUserId string             `bson:"blockedUserId,omitempty" json:"blockedUserId,omitempty"`
+		AppId         string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		CreateAt      time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+func blockKey(ctx context.Context, userId string, blockedUserId string) string {
+	return BlockKeyPrefix + identity.CacheKeyPrefix(ctx) + userId + ":" + blockedUserId
+}
+
+// blockFilter 屏蔽关系按应用隔离，upsert 时 appId 随查询条件写入
+func blockFilter(ctx context.Context, userId string, blockedUserId string) bson.M {
+	return identity.Scope(ctx, bson.M{
+		consts.UserId:        userId,
+		consts.BlockedUserId: blockedUserId,
+	})
+}
+
+func (m *MongoMapper) Block(ctx context.Context, userId string, blockedUserId string) error {
+	defer metrics.ObserveMongo(CollectionName, "Block", time.Now())
+	_, err := m.conn.UpdateOne(ctx, blockKey(ctx, userId, blockedUserId), blockFilter(ctx, userId, blockedUserId), bson.M{
+		"$setOnInsert": bson.M{consts.CreateAt: time.Now()},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+func (m *MongoMapper) Unblock(ctx context.Context, userId string, blockedUserId string) error {
+	defer metrics.ObserveMongo(CollectionName, "Unblock", time.Now())
+	_, err := m.conn.DeleteOne(ctx, blockKey(ctx, userId, blockedUserId), blockFilter(ctx, userId, blockedUserId))
+	return err
+}
+
+// IsBlocked 查询结果（包括不存在）都会被缓存，屏蔽关系变更时清除对应缓存
+func (m *MongoMapper) IsBlocked(ctx context.Context, userId string, blockedUserId string) (bool, error) {
+	defer metrics.ObserveMongo(CollectionName, "IsBlocked", time.Now())
+	var data Block
+	err := m.conn.FindOne(ctx, blockKey(ctx, userId, blockedUserId), &data, blockFilter(ctx, userId, blockedUserId))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return false, nil
+	case err == nil:
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+func (m *MongoMapper) GetBlockedUserIds(ctx context.Context, userId string) ([]string, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetBlockedUserIds", time.Now())
+	var data []*Block
+	if err := m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{consts.UserId: userId}), &options.FindOptions{
+		Sort: bson.M{consts.ID: -1},
+	}); err != nil {
+		return nil, err
+	}
+	return lo.Map[*Block, string](data, func(item *Block, _ int) string {
+		return item.BlockedUserId
+	}), nil
+}
+
+func NewBlockModel(config *config.Config) IBlockMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	return &MongoMapper{
+		conn: conn,
+	}
+}
@@ -0,0 +1,115 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName = "delivery"
+)
+
+var _ IDeliveryMongoMapper = (*MongoMapper)(nil)
+
+type (
+	IDeliveryMongoMapper interface {
+		InsertMany(ctx context.Context, data []*Delivery) error
+		ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*Delivery, error)
+		UpdateOne(ctx context.Context, data *Delivery) error
+		GetDeliveries(ctx context.Context, notificationId string) ([]*Delivery, error)
+	}
+	Delivery struct {
+		ID             primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		NotificationId string             `bson:"notificationId,omitempty" json:"notificationId,omitempty"`
+		TargetUserId   string             `bson:"targetUserId,omitempty" json:"targetUserId,omitempty"`
+		Channel        string             `bson:"channel,omitempty" json:"channel,omitempty"`
+		Type           int64              `bson:"type,omitempty" json:"type,omitempty"`
+		Text           string             `bson:"text,omitempty" json:"text,omitempty"`
+		Status         int64              `bson:"status,omitempty" json:"status,omitempty"`
+		Attempts       int64              `bson:"attempts,omitempty" json:"attempts,omitempty"`
+		LastError      string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+		NextAttemptAt  time.Time          `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+		CreateAt       time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+		AppId          string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		UpdateAt       time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+func (m *MongoMapper) InsertMany(ctx context.Context, data []*Delivery) error {
+	defer metrics.ObserveMongo(CollectionName, "InsertMany", time.Now())
+	if len(data) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, d := range data {
+		if d.ID.IsZero() {
+			d.ID = primitive.NewObjectID()
+		}
+		d.CreateAt = now
+		d.UpdateAt = now
+		d.AppId = identity.FromContext(ctx).AppId
+	}
+	_, err := m.conn.InsertMany(ctx, lo.ToAnySlice(data))
+	return err
+}
+
+// ClaimDue 领取一条到期的待投递记录，并把下次投递时间推后 lease 防止被重复领取
+func (m *MongoMapper) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*Delivery, error) {
+	defer metrics.ObserveMongo(CollectionName, "ClaimDue", time.Now())
+	var data Delivery
+	err := m.conn.FindOneAndUpdateNoCache(ctx, &data, identity.Scope(ctx, bson.M{
+		consts.Status:        consts.DeliveryStatusPending,
+		consts.NextAttemptAt: bson.M{"$lte": now},
+	}), bson.M{
+		"$set": bson.M{consts.NextAttemptAt: now.Add(lease), consts.UpdateAt: now},
+	}, options.FindOneAndUpdate().SetSort(bson.M{consts.NextAttemptAt: 1}).SetReturnDocument(options.After))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return nil, consts.ErrNotFound
+	case err == nil:
+		return &data, nil
+	default:
+		return nil, err
+	}
+}
+
+func (m *MongoMapper) UpdateOne(ctx context.Context, data *Delivery) error {
+	defer metrics.ObserveMongo(CollectionName, "UpdateOne", time.Now())
+	data.UpdateAt = time.Now()
+	_, err := m.conn.UpdateOneNoCache(ctx, identity.Scope(ctx, bson.M{consts.ID: data.ID}), bson.M{"$set": data})
+	return err
+}
+
+func (m *MongoMapper) GetDeliveries(ctx context.Context, notificationId string) ([]*Delivery, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetDeliveries", time.Now())
+	var data []*Delivery
+	if err := m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{consts.NotificationId: notificationId}), &options.FindOptions{
+		Sort: bson.M{consts.ID: 1},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func NewDeliveryModel(config *config.Config) IDeliveryMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	return &MongoMapper{
+		conn: conn,
+	}
+}
@@ -0,0 +1,80 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+var (
+	_ mongop.MongoCursor = (*CreateAtCursor)(nil)
+	_ mongop.MongoCursor = (*SortAtCursor)(nil)
+)
+
+// CreateAtCursor 按创建时间分页的游标，创建时间相同的通知再按 id 排序
+type CreateAtCursor struct {
+	ID       string    `json:"_id"`
+	CreateAt time.Time `json:"createAt"`
+}
+
+var CreateAtCursorType = (*CreateAtCursor)(nil)
+
+func (s *CreateAtCursor) MakeSortOptions(filter bson.M, backward bool) (bson.M, error) {
+	if s == nil {
+		return makeTimeSort(consts.CreateAt, "", time.Time{}, filter, backward)
+	}
+	return makeTimeSort(consts.CreateAt, s.ID, s.CreateAt, filter, backward)
+}
+
+// SortAtCursor 按排序时间分页的游标，被唤醒的通知排在最前，排序时间相同的通知再按 id 排序
+type SortAtCursor struct {
+	ID     string    `json:"_id"`
+	SortAt time.Time `json:"sortAt"`
+}
+
+var SortAtCursorType = (*SortAtCursor)(nil)
+
+func (s *SortAtCursor) MakeSortOptions(filter bson.M, backward bool) (bson.M, error) {
+	if s == nil {
+		return makeTimeSort(consts.SortAt, "", time.Time{}, filter, backward)
+	}
+	return makeTimeSort(consts.SortAt, s.ID, s.SortAt, filter, backward)
+}
+
+// makeTimeSort 先按时间字段 key 再按 id 排序，id 为空表示第一页
+func makeTimeSort(key string, id string, t time.Time, filter bson.M, backward bool) (bson.M, error) {
+	op, dir := "$lt", -1
+	if backward {
+		op, dir = "$gt", 1
+	}
+	sort := bson.M{key: dir, consts.ID: dir}
+	if id == "" {
+		return sort, nil
+	}
+
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+	// 避免与关键词检索等条件中的 $or 冲突，统一放进 $and
+	and, _ := filter["$and"].([]bson.M)
+	filter["$and"] = append(and, bson.M{"$or": []bson.M{
+		{key: bson.M{op: t}},
+		{key: t, consts.ID: bson.M{op: oid}},
+	}})
+	return sort, nil
+}
+
+// orderedSort bson.M 中多个排序键的顺序不确定，按时间游标排序时固定为先时间后 id
+func orderedSort(sort bson.M) any {
+	for _, key := range []string{consts.SortAt, consts.CreateAt} {
+		if dir, ok := sort[key]; ok {
+			return bson.D{{Key: key, Value: dir}, {Key: consts.ID, Value: sort[consts.ID]}}
+		}
+	}
+	return sort
+}
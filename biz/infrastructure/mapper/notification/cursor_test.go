@@ -0,0 +1,193 @@
+package notification
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+func TestCreateAtCursorMakeSortOptions(t *testing.T) {
+	id := primitive.NewObjectID()
+	createAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		cursor     *CreateAtCursor
+		filter     bson.M
+		backward   bool
+		wantSort   bson.M
+		wantFilter bson.M
+		wantErr    bool
+	}{
+		{
+			name:       "first page",
+			filter:     bson.M{consts.TargetUserId: "u"},
+			wantSort:   bson.M{consts.CreateAt: -1, consts.ID: -1},
+			wantFilter: bson.M{consts.TargetUserId: "u"},
+		},
+		{
+			name:     "forward",
+			cursor:   &CreateAtCursor{ID: id.Hex(), CreateAt: createAt},
+			filter:   bson.M{consts.TargetUserId: "u"},
+			wantSort: bson.M{consts.CreateAt: -1, consts.ID: -1},
+			wantFilter: bson.M{
+				consts.TargetUserId: "u",
+				"$and": []bson.M{{"$or": []bson.M{
+					{consts.CreateAt: bson.M{"$lt": createAt}},
+					{consts.CreateAt: createAt, consts.ID: bson.M{"$lt": id}},
+				}}},
+			},
+		},
+		{
+			name:     "backward",
+			cursor:   &CreateAtCursor{ID: id.Hex(), CreateAt: createAt},
+			filter:   bson.M{},
+			backward: true,
+			wantSort: bson.M{consts.CreateAt: 1, consts.ID: 1},
+			wantFilter: bson.M{
+				"$and": []bson.M{{"$or": []bson.M{
+					{consts.CreateAt: bson.M{"$gt": createAt}},
+					{consts.CreateAt: createAt, consts.ID: bson.M{"$gt": id}},
+				}}},
+			},
+		},
+		{
+			name:     "keeps existing $and",
+			cursor:   &CreateAtCursor{ID: id.Hex(), CreateAt: createAt},
+			filter:   bson.M{"$and": []bson.M{{consts.Type: int64(1)}}},
+			wantSort: bson.M{consts.CreateAt: -1, consts.ID: -1},
+			wantFilter: bson.M{
+				"$and": []bson.M{
+					{consts.Type: int64(1)},
+					{"$or": []bson.M{
+						{consts.CreateAt: bson.M{"$lt": createAt}},
+						{consts.CreateAt: createAt, consts.ID: bson.M{"$lt": id}},
+					}},
+				},
+			},
+		},
+		{
+			name:    "invalid id",
+			cursor:  &CreateAtCursor{ID: "invalid", CreateAt: createAt},
+			filter:  bson.M{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := tt.cursor.MakeSortOptions(tt.filter, tt.backward)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(sort, tt.wantSort) {
+				t.Errorf("sort = %v, want %v", sort, tt.wantSort)
+			}
+			if !reflect.DeepEqual(tt.filter, tt.wantFilter) {
+				t.Errorf("filter = %v, want %v", tt.filter, tt.wantFilter)
+			}
+		})
+	}
+}
+
+func TestSortAtCursorMakeSortOptions(t *testing.T) {
+	id := primitive.NewObjectID()
+	sortAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		cursor     *SortAtCursor
+		filter     bson.M
+		backward   bool
+		wantSort   bson.M
+		wantFilter bson.M
+	}{
+		{
+			name:       "first page",
+			filter:     bson.M{consts.TargetUserId: "u"},
+			wantSort:   bson.M{consts.SortAt: -1, consts.ID: -1},
+			wantFilter: bson.M{consts.TargetUserId: "u"},
+		},
+		{
+			name:     "forward",
+			cursor:   &SortAtCursor{ID: id.Hex(), SortAt: sortAt},
+			filter:   bson.M{consts.TargetUserId: "u"},
+			wantSort: bson.M{consts.SortAt: -1, consts.ID: -1},
+			wantFilter: bson.M{
+				consts.TargetUserId: "u",
+				"$and": []bson.M{{"$or": []bson.M{
+					{consts.SortAt: bson.M{"$lt": sortAt}},
+					{consts.SortAt: sortAt, consts.ID: bson.M{"$lt": id}},
+				}}},
+			},
+		},
+		{
+			name:     "backward",
+			cursor:   &SortAtCursor{ID: id.Hex(), SortAt: sortAt},
+			filter:   bson.M{},
+			backward: true,
+			wantSort: bson.M{consts.SortAt: 1, consts.ID: 1},
+			wantFilter: bson.M{
+				"$and": []bson.M{{"$or": []bson.M{
+					{consts.SortAt: bson.M{"$gt": sortAt}},
+					{consts.SortAt: sortAt, consts.ID: bson.M{"$gt": id}},
+				}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sort, err := tt.cursor.MakeSortOptions(tt.filter, tt.backward)
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if !reflect.DeepEqual(sort, tt.wantSort) {
+				t.Errorf("sort = %v, want %v", sort, tt.wantSort)
+			}
+			if !reflect.DeepEqual(tt.filter, tt.wantFilter) {
+				t.Errorf("filter = %v, want %v", tt.filter, tt.wantFilter)
+			}
+		})
+	}
+}
+
+func TestOrderedSort(t *testing.T) {
+	tests := []struct {
+		name string
+		sort bson.M
+		want any
+	}{
+		{
+			name: "id only",
+			sort: bson.M{consts.ID: -1},
+			want: bson.M{consts.ID: -1},
+		},
+		{
+			name: "createAt descending",
+			sort: bson.M{consts.ID: -1, consts.CreateAt: -1},
+			want: bson.D{{Key: consts.CreateAt, Value: -1}, {Key: consts.ID, Value: -1}},
+		},
+		{
+			name: "createAt ascending",
+			sort: bson.M{consts.CreateAt: 1, consts.ID: 1},
+			want: bson.D{{Key: consts.CreateAt, Value: 1}, {Key: consts.ID, Value: 1}},
+		},
+		{
+			name: "sortAt descending",
+			sort: bson.M{consts.ID: -1, consts.SortAt: -1},
+			want: bson.D{{Key: consts.SortAt, Value: -1}, {Key: consts.ID, Value: -1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := orderedSort(tt.sort); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderedSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,13 @@
 package notification
 
 import (
+	"context"
+	"fmt"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"regexp"
+	"time"
+
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
 	"github.com/samber/lo"
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,6 +19,27 @@ type FilterOptions struct {
 	OnlyUserIds         []string
 	OnlyType            *int64
 	OnlyNotificationIds []string
+	OnlyNeedAck         *bool
+	OnlyCreateAtAfter   *time.Time
+	OnlyCreateAtBefore  *time.Time
+	// OnlySortAtAfter 只查询排序时间晚于该时间的通知，用于判断未读
+	OnlySortAtAfter     *time.Time
+	OnlyUpdateAtAfter   *time.Time
+	OnlySourceUserId    *string
+	OnlySourceContentId *string
+	OnlyStarred         *bool
+	// OnlyFolder inbox、archived 或用户自定义的标签
+	OnlyFolder *string
+	// OnlyLabelsFewerThan 只查询自定义标签数少于该值的通知
+	OnlyLabelsFewerThan *int64
+	// OnlyKeyword 检索文案中包含该关键词的通知
+	OnlyKeyword *string
+	// IncludeSnoozed 为 true 时不排除仍在稍后提醒中的通知
+	IncludeSnoozed bool
+	// IncludeHidden 为 true 时不排除因屏蔽来源用户而隐藏的通知
+	IncludeHidden bool
+	// IncludeRetracted 为 true 时不排除被管理员撤回的通知
+	IncludeRetracted bool
 }
 
 type MongoFilter struct {
@@ -19,11 +47,27 @@ type MongoFilter struct {
 	*FilterOptions
 }
 
-func MakeBsonFilter(options *FilterOptions) bson.M {
-	return (&MongoFilter{
+// MakeBsonFilter 生成查询条件，并按 ctx 中的应用隔离数据
+func MakeBsonFilter(ctx context.Context, options *FilterOptions) bson.M {
+	f := &MongoFilter{
+		m:             bson.M{},
+		FilterOptions: options,
+	}
+	f.toBson()
+	f.CheckAppId(ctx)
+	return f.m
+}
+
+// IsEmptyFilter 除应用隔离外没有任何查询条件
+func IsEmptyFilter(options *FilterOptions) bool {
+	return len((&MongoFilter{
 		m:             bson.M{},
 		FilterOptions: options,
-	}).toBson()
+	}).toBson()) == 0
+}
+
+func (f *MongoFilter) CheckAppId(ctx context.Context) {
+	identity.Scope(ctx, f.m)
 }
 
 func (f *MongoFilter) toBson() bson.M {
@@ -31,6 +75,20 @@ func (f *MongoFilter) toBson() bson.M {
 	f.CheckOnlyType()
 	f.CheckOnlyUserIds()
 	f.CheckOnlyNotificationIds()
+	f.CheckOnlyNeedAck()
+	f.CheckOnlyCreateAtAfter()
+	f.CheckOnlyCreateAtBefore()
+	f.CheckOnlySortAtAfter()
+	f.CheckOnlyUpdateAtAfter()
+	f.CheckOnlySourceUserId()
+	f.CheckOnlySourceContentId()
+	f.CheckOnlyStarred()
+	f.CheckOnlyFolder()
+	f.CheckOnlyLabelsFewerThan()
+	f.CheckOnlyKeyword()
+	f.CheckSnoozed()
+	f.CheckHidden()
+	f.CheckRetracted()
 	return f.m
 }
 
@@ -63,3 +121,111 @@ func (f *MongoFilter) CheckOnlyUserIds() {
 		}
 	}
 }
+
+func (f *MongoFilter) CheckOnlyNeedAck() {
+	if f.OnlyNeedAck != nil {
+		if *f.OnlyNeedAck {
+			f.m[consts.NeedAck] = true
+		} else {
+			f.m[consts.NeedAck] = bson.M{"$ne": true}
+		}
+	}
+}
+
+func (f *MongoFilter) CheckOnlyCreateAtAfter() {
+	if f.OnlyCreateAtAfter != nil {
+		f.m[consts.CreateAt] = bson.M{"$gt": *f.OnlyCreateAtAfter}
+	}
+}
+
+func (f *MongoFilter) CheckOnlySortAtAfter() {
+	if f.OnlySortAtAfter != nil {
+		f.m[consts.SortAt] = bson.M{"$gt": *f.OnlySortAtAfter}
+	}
+}
+
+func (f *MongoFilter) CheckOnlyCreateAtBefore() {
+	if f.OnlyCreateAtBefore != nil {
+		if m, ok := f.m[consts.CreateAt].(bson.M); ok {
+			m["$lt"] = *f.OnlyCreateAtBefore
+		} else {
+			f.m[consts.CreateAt] = bson.M{"$lt": *f.OnlyCreateAtBefore}
+		}
+	}
+}
+
+func (f *MongoFilter) CheckOnlyUpdateAtAfter() {
+	if f.OnlyUpdateAtAfter != nil {
+		f.m[consts.UpdateAt] = bson.M{"$gt": *f.OnlyUpdateAtAfter}
+	}
+}
+
+func (f *MongoFilter) CheckOnlySourceUserId() {
+	if f.OnlySourceUserId != nil {
+		f.m[consts.SourceUserId] = *f.OnlySourceUserId
+	}
+}
+
+func (f *MongoFilter) CheckOnlySourceContentId() {
+	if f.OnlySourceContentId != nil {
+		f.m[consts.SourceContentId] = *f.OnlySourceContentId
+	}
+}
+
+func (f *MongoFilter) CheckOnlyStarred() {
+	if f.OnlyStarred != nil {
+		if *f.OnlyStarred {
+			f.m[consts.IsStarred] = true
+		} else {
+			f.m[consts.IsStarred] = bson.M{"$ne": true}
+		}
+	}
+}
+
+func (f *MongoFilter) CheckSnoozed() {
+	if !f.IncludeSnoozed {
+		f.m[consts.SnoozeUntil] = bson.M{"$not": bson.M{"$gt": time.Now()}}
+	}
+}
+
+func (f *MongoFilter) CheckHidden() {
+	if !f.IncludeHidden {
+		f.m[consts.IsHidden] = bson.M{"$ne": true}
+	}
+}
+
+func (f *MongoFilter) CheckRetracted() {
+	if !f.IncludeRetracted {
+		f.m[consts.IsRetracted] = bson.M{"$ne": true}
+	}
+}
+
+func (f *MongoFilter) CheckOnlyFolder() {
+	if f.OnlyFolder != nil {
+		switch *f.OnlyFolder {
+		case consts.InboxFolder:
+			f.m[consts.IsArchived] = bson.M{"$ne": true}
+		case consts.ArchivedFolder:
+			f.m[consts.IsArchived] = true
+		default:
+			f.m[consts.Labels] = *f.OnlyFolder
+		}
+	}
+}
+
+// CheckOnlyLabelsFewerThan 第 n 个标签不存在即标签数少于 n
+func (f *MongoFilter) CheckOnlyLabelsFewerThan() {
+	if f.OnlyLabelsFewerThan != nil {
+		f.m[fmt.Sprintf("%s.%d", consts.Labels, *f.OnlyLabelsFewerThan-1)] = bson.M{"$exists": false}
+	}
+}
+
+// CheckOnlyKeyword 先用分词索引缩小范围，再用正则保证关键词连续出现，旧通知的分词由 BackfillGrams 补齐
+func (f *MongoFilter) CheckOnlyKeyword() {
+	if f.OnlyKeyword != nil {
+		if grams := makeQueryGrams(*f.OnlyKeyword); len(grams) > 0 {
+			f.m[consts.Grams] = bson.M{"$all": grams}
+		}
+		f.m[consts.Text] = primitive.Regex{Pattern: regexp.QuoteMeta(*f.OnlyKeyword), Options: "i"}
+	}
+}
@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samber/lo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+)
+
+func TestMakeBsonFilterAppId(t *testing.T) {
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		fopts     *FilterOptions
+		wantScope bool
+		wantAppId any
+	}{
+		{name: "no identity is default app", ctx: context.Background(), fopts: &FilterOptions{}, wantScope: true, wantAppId: nil},
+		{name: "caller of default app", ctx: identity.WithIdentity(context.Background(), &identity.Identity{Caller: "c"}), fopts: &FilterOptions{}, wantScope: true, wantAppId: nil},
+		{name: "caller of app", ctx: identity.WithIdentity(context.Background(), &identity.Identity{AppId: "a"}), fopts: &FilterOptions{}, wantScope: true, wantAppId: "a"},
+		{name: "job of app", ctx: identity.AppContext(context.Background(), "b"), fopts: &FilterOptions{OnlyUserId: lo.ToPtr("u")}, wantScope: true, wantAppId: "b"},
+		{name: "job of default app", ctx: identity.AppContext(context.Background(), ""), fopts: &FilterOptions{}, wantScope: true, wantAppId: nil},
+		{name: "system job", ctx: identity.SystemContext(), fopts: &FilterOptions{}, wantScope: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := MakeBsonFilter(tt.ctx, tt.fopts)
+			appId, ok := filter[consts.AppId]
+			if ok != tt.wantScope || appId != tt.wantAppId {
+				t.Fatalf("appId = %v, %v, want %v, %v", appId, ok, tt.wantAppId, tt.wantScope)
+			}
+			if tt.fopts.OnlyUserId != nil && filter[consts.TargetUserId] != *tt.fopts.OnlyUserId {
+				t.Fatalf("targetUserId = %v, want %v", filter[consts.TargetUserId], *tt.fopts.OnlyUserId)
+			}
+		})
+	}
+}
+
+func TestIsEmptyFilterIgnoresAppId(t *testing.T) {
+	tests := []struct {
+		name  string
+		fopts *FilterOptions
+		want  bool
+	}{
+		{name: "empty", fopts: &FilterOptions{IncludeSnoozed: true, IncludeHidden: true, IncludeRetracted: true}, want: true},
+		{name: "user", fopts: &FilterOptions{OnlyUserId: lo.ToPtr("u"), IncludeSnoozed: true, IncludeHidden: true, IncludeRetracted: true}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEmptyFilter(tt.fopts); got != tt.want {
+				t.Fatalf("IsEmptyFilter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
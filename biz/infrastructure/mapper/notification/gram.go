@@ -0,0 +1,59 @@
+package notification
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/samber/lo"
+)
+
+// makeGrams 生成文本的一元与二元分词，用于不依赖分词器的中文检索
+func makeGrams(text string) []string {
+	var grams []string
+	for _, seg := range splitSegments(text) {
+		for i := range seg {
+			grams = append(grams, string(seg[i]))
+			if i+1 < len(seg) {
+				grams = append(grams, string(seg[i:i+2]))
+			}
+		}
+	}
+	return lo.Uniq(grams)
+}
+
+// makeQueryGrams 生成检索词需要全部命中的分词，单字检索使用一元分词，否则使用二元分词
+func makeQueryGrams(query string) []string {
+	var grams []string
+	for _, seg := range splitSegments(query) {
+		if len(seg) == 1 {
+			grams = append(grams, string(seg))
+			continue
+		}
+		for i := 0; i+1 < len(seg); i++ {
+			grams = append(grams, string(seg[i:i+2]))
+		}
+	}
+	return lo.Uniq(grams)
+}
+
+// splitSegments 转为小写并按空白与标点切分
+func splitSegments(text string) [][]rune {
+	var (
+		segs [][]rune
+		cur  []rune
+	)
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			cur = append(cur, r)
+			continue
+		}
+		if len(cur) > 0 {
+			segs = append(segs, cur)
+			cur = nil
+		}
+	}
+	if len(cur) > 0 {
+		segs = append(segs, cur)
+	}
+	return segs
+}
@@ -2,17 +2,24 @@ package notification
 
 import (
 	"context"
+	"errors"
 	"github.com/CloudStriver/go-pkg/utils/pagination"
 	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"github.com/CloudStriver/go-pkg/utils/util/log"
 	"github.com/samber/lo"
 	"github.com/zeromicro/go-zero/core/mr"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"time"
 
 	"github.com/zeromicro/go-zero/core/stores/monc"
 
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
 )
 
 const (
@@ -27,7 +34,20 @@ type (
 		Count(ctx context.Context, fopts *FilterOptions) (int64, error)
 		DeleteNotifications(ctx context.Context, fopts *FilterOptions) error
 		InsertOne(ctx context.Context, data *Notification) error
+		FindMany(ctx context.Context, fopts *FilterOptions) ([]*Notification, error)
+		FindBatch(ctx context.Context, fopts *FilterOptions, after primitive.ObjectID, limit int64) ([]*Notification, error)
+		MergeLatest(ctx context.Context, fopts *FilterOptions) (bool, error)
+		UpdateNotifications(ctx context.Context, fopts *FilterOptions, uopts *UpdateOptions) (int64, error)
+		Wake(ctx context.Context, now time.Time) ([]*Notification, error)
+		CountByFolder(ctx context.Context, fopts *FilterOptions) (*FolderCount, error)
+		GetNthNotification(ctx context.Context, fopts *FilterOptions, n int64) (*Notification, error)
+		GetLatestNotification(ctx context.Context, fopts *FilterOptions) (*Notification, error)
+		BackfillGrams(ctx context.Context, limit int64) (int64, error)
+		BackfillSortAt(ctx context.Context) (int64, error)
 		GetNotificationsAndCount(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*Notification, int64, error)
+		ScanTargetUserIds(ctx context.Context, batchSize int, fn func(userIds []string) error) error
+		GetAppIds(ctx context.Context) ([]string, error)
+		CountCreatedByType(ctx context.Context, from time.Time, to time.Time) ([]*TypeCount, error)
 	}
 	Notification struct {
 		ID              primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
@@ -37,8 +57,52 @@ type (
 		Type            int64              `bson:"type,omitempty" json:"type,omitempty"`
 		TargetType      int64              `bson:"targetType,omitempty" json:"targetType,omitempty"`
 		Text            string             `bson:"text,omitempty" json:"text,omitempty"`
+		Payload         string             `bson:"payload,omitempty" json:"payload,omitempty"`
+		NeedAck         bool               `bson:"needAck,omitempty" json:"needAck,omitempty"`
+		MergeCount      int64              `bson:"mergeCount,omitempty" json:"mergeCount,omitempty"`
+		NeedReview      bool               `bson:"needReview,omitempty" json:"needReview,omitempty"`
+		IsStarred       bool               `bson:"isStarred,omitempty" json:"isStarred,omitempty"`
+		SnoozeUntil     time.Time          `bson:"snoozeUntil,omitempty" json:"snoozeUntil,omitempty"`
+		WokeAt          time.Time          `bson:"wokeAt,omitempty" json:"wokeAt,omitempty"`
+		IsArchived      bool               `bson:"isArchived,omitempty" json:"isArchived,omitempty"`
+		Labels          []string           `bson:"labels,omitempty" json:"labels,omitempty"`
+		Grams           []string           `bson:"grams,omitempty" json:"-"`
+		IsHidden        bool               `bson:"isHidden,omitempty" json:"isHidden,omitempty"`
+		IsRetracted     bool               `bson:"isRetracted,omitempty" json:"isRetracted,omitempty"`
+		AppId           string             `bson:"appId,omitempty" json:"appId,omitempty"`
 		CreateAt        time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
 		UpdateAt        time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+		// SortAt 列表排序和未读判断使用的时间，创建时等于 CreateAt，被唤醒时更新为唤醒时间
+		SortAt time.Time `bson:"sortAt,omitempty" json:"sortAt,omitempty"`
+	}
+	// UpdateOptions 需要修改的字段，为 nil 的字段保持不变
+	UpdateOptions struct {
+		Text        *string
+		Payload     *string
+		Type        *int64
+		NeedReview  *bool
+		IsStarred   *bool
+		SnoozeUntil *time.Time
+		IsArchived  *bool
+		AddLabel    *string
+		RemoveLabel *string
+		IsHidden    *bool
+		IsRetracted *bool
+	}
+	// FolderCount 各文件夹中的通知数
+	FolderCount struct {
+		Inbox    int64
+		Archived int64
+		Labels   map[string]int64
+	}
+	// TypeCount 各通知类型、目标类型的通知数，Broadcast 区分全站广播和发给单个用户的通知
+	TypeCount struct {
+		ID struct {
+			Type       int64 `bson:"type"`
+			TargetType int64 `bson:"targetType"`
+			Broadcast  bool  `bson:"broadcast"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
 	}
 	MongoMapper struct {
 		conn *monc.Model
@@ -46,12 +110,14 @@ type (
 )
 
 func (m *MongoMapper) DeleteNotifications(ctx context.Context, fopts *FilterOptions) error {
-	filter := MakeBsonFilter(fopts)
+	defer metrics.ObserveMongo(CollectionName, "DeleteNotifications", time.Now())
+	filter := MakeBsonFilter(ctx, fopts)
 	_, err := m.conn.DeleteMany(ctx, filter)
 	return err
 }
 
 func (m *MongoMapper) GetNotificationsAndCount(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*Notification, int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetNotificationsAndCount", time.Now())
 	var (
 		data       []*Notification
 		count      int64
@@ -76,26 +142,31 @@ func (m *MongoMapper) GetNotificationsAndCount(ctx context.Context, fopts *Filte
 	return data, count, nil
 }
 func (m *MongoMapper) InsertOne(ctx context.Context, data *Notification) error {
+	defer metrics.ObserveMongo(CollectionName, "InsertOne", time.Now())
 	if data.ID.IsZero() {
 		data.ID = primitive.NewObjectID()
 	}
 	data.CreateAt = time.Now()
 	data.UpdateAt = time.Now()
+	data.SortAt = data.CreateAt
+	data.Grams = makeGrams(data.Text)
+	data.AppId = identity.FromContext(ctx).AppId
 
 	_, err := m.conn.InsertOneNoCache(ctx, data)
 	return err
 }
 func (m *MongoMapper) GetNotifications(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*Notification, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetNotifications", time.Now())
 	var data []*Notification
 	p := mongop.NewMongoPaginator(pagination.NewRawStore(sorter), popts)
-	filter := MakeBsonFilter(fopts)
+	filter := MakeBsonFilter(ctx, fopts)
 	sort, err := p.MakeSortOptions(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
 	if err = m.conn.Find(ctx, &data, filter, &options.FindOptions{
-		Sort:  sort,
+		Sort:  orderedSort(sort),
 		Limit: popts.Limit,
 		Skip:  popts.Offset,
 	}); err != nil {
@@ -115,13 +186,340 @@ func (m *MongoMapper) GetNotifications(ctx context.Context, fopts *FilterOptions
 	return data, nil
 }
 
+// FindMany 不分页地查询全部满足条件的通知，按创建时间倒序
+func (m *MongoMapper) FindMany(ctx context.Context, fopts *FilterOptions) ([]*Notification, error) {
+	defer metrics.ObserveMongo(CollectionName, "FindMany", time.Now())
+	var data []*Notification
+	filter := MakeBsonFilter(ctx, fopts)
+	if err := m.conn.Find(ctx, &data, filter, &options.FindOptions{
+		Sort: bson.M{consts.ID: -1},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// FindBatch 按 _id 升序返回 after 之后最多 limit 条满足条件的通知，只包含 id、用户和应用，用于分批处理大量通知
+func (m *MongoMapper) FindBatch(ctx context.Context, fopts *FilterOptions, after primitive.ObjectID, limit int64) ([]*Notification, error) {
+	defer metrics.ObserveMongo(CollectionName, "FindBatch", time.Now())
+	var data []*Notification
+	filter := MakeBsonFilter(ctx, fopts)
+	if !after.IsZero() {
+		// 条件中可能已经有按 id 的筛选，不能直接覆盖
+		filter = bson.M{"$and": bson.A{filter, bson.M{consts.ID: bson.M{"$gt": after}}}}
+	}
+	if err := m.conn.Find(ctx, &data, filter, &options.FindOptions{
+		Sort:       bson.M{consts.ID: 1},
+		Limit:      lo.ToPtr(limit),
+		Projection: bson.M{consts.ID: 1, consts.TargetUserId: 1, consts.AppId: 1},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// MergeLatest 将一条新通知合并到最近一条满足条件的通知上，返回是否找到可合并的通知
+func (m *MongoMapper) MergeLatest(ctx context.Context, fopts *FilterOptions) (bool, error) {
+	defer metrics.ObserveMongo(CollectionName, "MergeLatest", time.Now())
+	var data Notification
+	filter := MakeBsonFilter(ctx, fopts)
+	err := m.conn.FindOneAndUpdateNoCache(ctx, &data, filter, bson.M{
+		"$inc": bson.M{consts.MergeCount: 1},
+		"$set": bson.M{consts.UpdateAt: time.Now()},
+	}, options.FindOneAndUpdate().SetSort(bson.M{consts.ID: -1}))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return false, nil
+	case err == nil:
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+func (m *MongoMapper) UpdateNotifications(ctx context.Context, fopts *FilterOptions, uopts *UpdateOptions) (int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "UpdateNotifications", time.Now())
+	update := bson.M{consts.UpdateAt: time.Now()}
+	if uopts.Text != nil {
+		update[consts.Text] = *uopts.Text
+		update[consts.Grams] = makeGrams(*uopts.Text)
+	}
+	if uopts.Payload != nil {
+		update[consts.Payload] = *uopts.Payload
+	}
+	if uopts.Type != nil {
+		update[consts.Type] = *uopts.Type
+	}
+	if uopts.NeedReview != nil {
+		update[consts.NeedReview] = *uopts.NeedReview
+	}
+	if uopts.IsStarred != nil {
+		update[consts.IsStarred] = *uopts.IsStarred
+	}
+	if uopts.SnoozeUntil != nil {
+		update[consts.SnoozeUntil] = *uopts.SnoozeUntil
+	}
+	if uopts.IsArchived != nil {
+		update[consts.IsArchived] = *uopts.IsArchived
+	}
+	if uopts.IsHidden != nil {
+		update[consts.IsHidden] = *uopts.IsHidden
+	}
+	if uopts.IsRetracted != nil {
+		update[consts.IsRetracted] = *uopts.IsRetracted
+	}
+	ops := bson.M{"$set": update}
+	if uopts.AddLabel != nil {
+		ops["$addToSet"] = bson.M{consts.Labels: *uopts.AddLabel}
+	}
+	if uopts.RemoveLabel != nil {
+		ops["$pull"] = bson.M{consts.Labels: *uopts.RemoveLabel}
+	}
+	filter := MakeBsonFilter(ctx, fopts)
+	res, err := m.conn.UpdateManyNoCache(ctx, filter, ops)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// Wake 原地唤醒所有已到提醒时间的通知：清除提醒时间并把排序时间改为 now，使其排在列表最前并重新计入未读，
+// 返回本次唤醒的通知。id 和创建时间保持不变，确认、投递、统计和保留时长都不受影响
+func (m *MongoMapper) Wake(ctx context.Context, now time.Time) ([]*Notification, error) {
+	defer metrics.ObserveMongo(CollectionName, "Wake", time.Now())
+	// Mongo 只保存到毫秒，截断后才能用 wokeAt 精确找回本次唤醒的通知
+	now = now.Truncate(time.Millisecond)
+	res, err := m.conn.UpdateManyNoCache(ctx, identity.Scope(ctx, bson.M{
+		consts.SnoozeUntil: bson.M{"$lte": now},
+	}), bson.M{
+		"$set": bson.M{
+			consts.SortAt:   now,
+			consts.UpdateAt: now,
+			consts.WokeAt:   now,
+		},
+		"$unset": bson.M{consts.SnoozeUntil: ""},
+	})
+	if err != nil || res.ModifiedCount == 0 {
+		return nil, err
+	}
+	var data []*Notification
+	if err = m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{
+		consts.WokeAt: now,
+		consts.SortAt: now,
+	})); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// CountByFolder 按收件箱、归档和标签分别统计满足条件的通知数
+func (m *MongoMapper) CountByFolder(ctx context.Context, fopts *FilterOptions) (*FolderCount, error) {
+	defer metrics.ObserveMongo(CollectionName, "CountByFolder", time.Now())
+	var data []struct {
+		Inbox    []struct{ N int64 } `bson:"inbox"`
+		Archived []struct{ N int64 } `bson:"archived"`
+		Labels   []struct {
+			ID string `bson:"_id"`
+			N  int64  `bson:"n"`
+		} `bson:"labels"`
+	}
+	if err := m.conn.Aggregate(ctx, &data, []bson.M{
+		{"$match": MakeBsonFilter(ctx, fopts)},
+		{"$facet": bson.M{
+			"inbox": []bson.M{
+				{"$match": bson.M{consts.IsArchived: bson.M{"$ne": true}}},
+				{"$count": "n"},
+			},
+			"archived": []bson.M{
+				{"$match": bson.M{consts.IsArchived: true}},
+				{"$count": "n"},
+			},
+			"labels": []bson.M{
+				{"$unwind": "$" + consts.Labels},
+				{"$group": bson.M{consts.ID: "$" + consts.Labels, "n": bson.M{"$sum": 1}}},
+			},
+		}},
+	}); err != nil {
+		return nil, err
+	}
+	res := &FolderCount{Labels: map[string]int64{}}
+	if len(data) == 0 {
+		return res, nil
+	}
+	for _, c := range data[0].Inbox {
+		res.Inbox = c.N
+	}
+	for _, c := range data[0].Archived {
+		res.Archived = c.N
+	}
+	for _, c := range data[0].Labels {
+		res.Labels[c.ID] = c.N
+	}
+	return res, nil
+}
+
+// GetNthNotification 按 id 倒序取第 n 条（从 1 开始）满足条件的通知，只用于把旧版本记录的已读数换算为已读时间
+func (m *MongoMapper) GetNthNotification(ctx context.Context, fopts *FilterOptions, n int64) (*Notification, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetNthNotification", time.Now())
+	var data []*Notification
+	if err := m.conn.Find(ctx, &data, MakeBsonFilter(ctx, fopts), &options.FindOptions{
+		Sort:  bson.M{consts.ID: -1},
+		Skip:  lo.ToPtr(n - 1),
+		Limit: lo.ToPtr(int64(1)),
+	}); err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, consts.ErrNotFound
+	}
+	return data[0], nil
+}
+
+// GetLatestNotification 排序时间最晚的一条满足条件的通知
+func (m *MongoMapper) GetLatestNotification(ctx context.Context, fopts *FilterOptions) (*Notification, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetLatestNotification", time.Now())
+	var data Notification
+	err := m.conn.FindOneNoCache(ctx, &data, MakeBsonFilter(ctx, fopts), options.FindOne().
+		SetSort(bson.D{{Key: consts.SortAt, Value: -1}, {Key: consts.ID, Value: -1}}))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return nil, consts.ErrNotFound
+	case err == nil:
+		return &data, nil
+	default:
+		return nil, err
+	}
+}
+
+// BackfillGrams 为最多 limit 条没有分词的旧通知补充分词，返回处理的条数，为 0 时已全部补齐
+func (m *MongoMapper) BackfillGrams(ctx context.Context, limit int64) (int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "BackfillGrams", time.Now())
+	var data []*Notification
+	if err := m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{consts.Grams: bson.M{"$exists": false}}), &options.FindOptions{
+		Limit:      lo.ToPtr(limit),
+		Projection: bson.M{consts.Text: 1},
+	}); err != nil || len(data) == 0 {
+		return 0, err
+	}
+	models := lo.Map[*Notification, mongo.WriteModel](data, func(item *Notification, _ int) mongo.WriteModel {
+		// 文案为空时写入空数组，避免被反复选中
+		return mongo.NewUpdateOneModel().
+			SetFilter(bson.M{consts.ID: item.ID}).
+			SetUpdate(bson.M{"$set": bson.M{consts.Grams: append([]string{}, makeGrams(item.Text)...)}})
+	})
+	if _, err := m.conn.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return 0, err
+	}
+	return int64(len(data)), nil
+}
+
+// BackfillSortAt 为旧版本写入的没有排序时间的通知以创建时间补上排序时间，返回处理的条数
+func (m *MongoMapper) BackfillSortAt(ctx context.Context) (int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "BackfillSortAt", time.Now())
+	res, err := m.conn.UpdateManyNoCache(ctx, identity.Scope(ctx, bson.M{consts.SortAt: bson.M{"$exists": false}}), mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{consts.SortAt: "$" + consts.CreateAt}}},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// ScanTargetUserIds 按游标逐批读出当前应用中收到过通知的全部用户（不包括全站广播），每批最多 batchSize 个交给 fn。
+// 用户数没有上限，不能用 distinct 一次返回，结果会超过单个文档 16MB 的限制
+func (m *MongoMapper) ScanTargetUserIds(ctx context.Context, batchSize int, fn func(userIds []string) error) error {
+	defer metrics.ObserveMongo(CollectionName, "ScanTargetUserIds", time.Now())
+	cur, err := m.conn.Collection.Aggregate(ctx, []bson.M{
+		{"$match": identity.Scope(ctx, bson.M{
+			consts.TargetUserId: bson.M{"$ne": consts.NotificationSystemKey},
+		})},
+		{"$group": bson.M{consts.ID: "$" + consts.TargetUserId}},
+	}, options.Aggregate().SetAllowDiskUse(true).SetBatchSize(int32(batchSize)))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	userIds := make([]string, 0, batchSize)
+	for cur.Next(ctx) {
+		var data struct {
+			ID string `bson:"_id"`
+		}
+		if err = cur.Decode(&data); err != nil {
+			return err
+		}
+		if userIds = append(userIds, data.ID); len(userIds) == batchSize {
+			if err = fn(userIds); err != nil {
+				return err
+			}
+			userIds = userIds[:0]
+		}
+	}
+	if err = cur.Err(); err != nil {
+		return err
+	}
+	if len(userIds) > 0 {
+		return fn(userIds)
+	}
+	return nil
+}
+
+// GetAppIds 有通知数据的全部应用，默认应用的数据没有 appId 字段，总是以空字符串排在第一个
+func (m *MongoMapper) GetAppIds(ctx context.Context) ([]string, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetAppIds", time.Now())
+	values, err := m.conn.Distinct(ctx, consts.AppId, bson.M{consts.AppId: bson.M{"$type": "string"}})
+	if err != nil {
+		return nil, err
+	}
+	return lo.Uniq(append([]string{""}, distinctStrings(values)...)), nil
+}
+
+func distinctStrings(values []any) []string {
+	return lo.FilterMap[any, string](values, func(item any, _ int) (string, bool) {
+		s, ok := item.(string)
+		return s, ok
+	})
+}
+
+// CountCreatedByType 统计当前应用 [from, to) 内创建的通知数，按通知类型、目标类型以及是否为全站广播分组
+func (m *MongoMapper) CountCreatedByType(ctx context.Context, from time.Time, to time.Time) ([]*TypeCount, error) {
+	defer metrics.ObserveMongo(CollectionName, "CountCreatedByType", time.Now())
+	var data []*TypeCount
+	if err := m.conn.Aggregate(ctx, &data, []bson.M{
+		{"$match": identity.Scope(ctx, bson.M{consts.CreateAt: bson.M{"$gte": from, "$lt": to}})},
+		{"$group": bson.M{
+			consts.ID: bson.M{
+				consts.Type:       "$" + consts.Type,
+				consts.TargetType: "$" + consts.TargetType,
+				consts.Broadcast:  bson.M{"$eq": bson.A{"$" + consts.TargetUserId, consts.NotificationSystemKey}},
+			},
+			"count": bson.M{"$sum": 1},
+		}},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 func (m *MongoMapper) Count(ctx context.Context, fopts *FilterOptions) (int64, error) {
-	f := MakeBsonFilter(fopts)
+	defer metrics.ObserveMongo(CollectionName, "Count", time.Now())
+	f := MakeBsonFilter(ctx, fopts)
 	return m.conn.CountDocuments(ctx, f)
 }
 
 func NewNotificationModel(config *config.Config) INotificationMongoMapper {
 	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	// 关键词检索按用户和分词命中索引，建索引失败只记录日志不影响启动
+	if _, err := conn.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: consts.TargetUserId, Value: 1}, {Key: consts.Grams, Value: 1}},
+	}); err != nil {
+		log.Error("[Notification] create grams index failed, err=%v", err)
+	}
+	// 通知列表默认按排序时间分页
+	if _, err := conn.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: consts.TargetUserId, Value: 1}, {Key: consts.SortAt, Value: -1}, {Key: consts.ID, Value: -1}},
+	}); err != nil {
+		log.Error("[Notification] create sortAt index failed, err=%v", err)
+	}
 	return &MongoMapper{
 		conn: conn,
 	}
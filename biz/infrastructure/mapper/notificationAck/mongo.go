@@ -0,0 +1,128 @@
+package notificationAck
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName   = "notificationAck"
+	duplicateKeyCode = 11000
+)
+
+var _ INotificationAckMongoMapper = (*MongoMapper)(nil)
+
+type (
+	INotificationAckMongoMapper interface {
+		Ack(ctx context.Context, userId string, notificationIds []string) error
+		GetAckedNotificationIds(ctx context.Context, userId string, notificationIds []string) ([]string, error)
+		GetAckStats(ctx context.Context, notificationIds []string) ([]*AckStat, error)
+	}
+	NotificationAck struct {
+		ID             primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		NotificationId string             `bson:"notificationId,omitempty" json:"notificationId,omitempty"`
+		UserId         string             `bson:"userId,omitempty" json:"userId,omitempty"`
+		AppId          string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		CreateAt       time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+	}
+	AckStat struct {
+		NotificationId string    `bson:"_id" json:"notificationId"`
+		Count          int64     `bson:"count" json:"count"`
+		FirstAckAt     time.Time `bson:"firstAckAt" json:"firstAckAt"`
+		LastAckAt      time.Time `bson:"lastAckAt" json:"lastAckAt"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+// Ack 记录用户对通知的确认，重复确认只保留第一次的时间
+func (m *MongoMapper) Ack(ctx context.Context, userId string, notificationIds []string) error {
+	defer metrics.ObserveMongo(CollectionName, "Ack", time.Now())
+	if len(notificationIds) == 0 {
+		return nil
+	}
+	now := time.Now()
+	models := lo.Map[string, mongo.WriteModel](lo.Uniq(notificationIds), func(id string, _ int) mongo.WriteModel {
+		return mongo.NewUpdateOneModel().
+			SetFilter(identity.Scope(ctx, bson.M{consts.NotificationId: id, consts.UserId: userId})).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{consts.CreateAt: now}}).
+			SetUpsert(true)
+	})
+	_, err := m.conn.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if onlyDuplicateKey(err) {
+		// 并发确认同一条通知时后到的 upsert 会违反唯一索引，此时记录已经存在
+		return nil
+	}
+	return err
+}
+
+func onlyDuplicateKey(err error) bool {
+	var e mongo.BulkWriteException
+	if !errors.As(err, &e) || e.WriteConcernError != nil || len(e.WriteErrors) == 0 {
+		return false
+	}
+	return lo.EveryBy(e.WriteErrors, func(we mongo.BulkWriteError) bool {
+		return we.Code == duplicateKeyCode
+	})
+}
+
+func (m *MongoMapper) GetAckedNotificationIds(ctx context.Context, userId string, notificationIds []string) ([]string, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetAckedNotificationIds", time.Now())
+	var data []*NotificationAck
+	if err := m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{
+		consts.UserId:         userId,
+		consts.NotificationId: bson.M{"$in": notificationIds},
+	})); err != nil {
+		return nil, err
+	}
+	return lo.Map[*NotificationAck, string](data, func(item *NotificationAck, _ int) string {
+		return item.NotificationId
+	}), nil
+}
+
+func (m *MongoMapper) GetAckStats(ctx context.Context, notificationIds []string) ([]*AckStat, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetAckStats", time.Now())
+	var data []*AckStat
+	if err := m.conn.Aggregate(ctx, &data, []bson.M{
+		{"$match": identity.Scope(ctx, bson.M{consts.NotificationId: bson.M{"$in": notificationIds}})},
+		{"$group": bson.M{
+			consts.ID:    "$" + consts.NotificationId,
+			"count":      bson.M{"$sum": 1},
+			"firstAckAt": bson.M{"$min": "$" + consts.CreateAt},
+			"lastAckAt":  bson.M{"$max": "$" + consts.CreateAt},
+		}},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func NewNotificationAckModel(config *config.Config) INotificationAckMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	// 同一用户对同一通知只能有一条确认记录，已有重复数据时建索引会失败，只记录日志不影响启动
+	if _, err := conn.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys:    bson.D{{Key: consts.NotificationId, Value: 1}, {Key: consts.UserId, Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		log.Error("[NotificationAck] create unique index failed, err=%v", err)
+	}
+	return &MongoMapper{
+		conn: conn,
+	}
+}
@@ -4,16 +4,24 @@ import (
 	"context"
 	"errors"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/samber/lo"
 	"github.com/zeromicro/go-zero/core/stores/monc"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"time"
 
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
 )
 
 const (
 	CollectionName       = "notificationCount"
 	NotificationCountKey = "cache:NotificationCount:"
+	backfillBatchSize    = 1000
 )
 
 var _ INotificationCountMongoMapper = (*MongoMapper)(nil)
@@ -21,47 +29,162 @@ var _ INotificationCountMongoMapper = (*MongoMapper)(nil)
 type (
 	INotificationCountMongoMapper interface {
 		GetNotificationCount(ctx context.Context, userId string) (int64, error)
+		GetReadState(ctx context.Context, userId string) (*NotificationCount, error)
+		UpdateReadAt(ctx context.Context, userId string, readAt time.Time) (bool, error)
 		UpdateNotificationCount(ctx context.Context, data *NotificationCount) error
 		CreateNotificationCount(ctx context.Context, data *NotificationCount) error
+		EnsureNotificationCounts(ctx context.Context, userIds []string) (int64, error)
+		CountUsers(ctx context.Context) (int64, error)
 	}
+	// NotificationCount 默认应用的记录以用户 id 为 _id，其他应用的记录按 userId、appId 区分。
+	// ReadAt 为已读时间，创建时间晚于它的通知为未读；Read 为旧版本记录的已读数，只在没有 ReadAt 时用于换算
 	NotificationCount struct {
-		ID   primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-		Read int64              `bson:"read,omitempty" json:"read,omitempty"`
+		ID     primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		Read   int64              `bson:"read,omitempty" json:"read,omitempty"`
+		ReadAt time.Time          `bson:"readAt,omitempty" json:"readAt,omitempty"`
+		UserId primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+		AppId  string             `bson:"appId,omitempty" json:"appId,omitempty"`
 	}
 	MongoMapper struct {
 		conn *monc.Model
 	}
 )
 
+// CreateNotificationCount 读写已读数时会自动创建记录，无需在注册时调用，重复调用不会覆盖已有的已读数
 func (m MongoMapper) CreateNotificationCount(ctx context.Context, data *NotificationCount) error {
-	key := NotificationCountKey + data.ID.Hex()
-	_, err := m.conn.InsertOne(ctx, key, data)
+	defer metrics.ObserveMongo(CollectionName, "CreateNotificationCount", time.Now())
+	_, err := m.conn.UpdateOne(ctx, cacheKey(ctx, data.ID.Hex()), countFilter(ctx, data.ID), bson.M{
+		"$setOnInsert": bson.M{consts.Read: data.Read},
+	}, options.Update().SetUpsert(true))
 	return err
 }
 
 func (m MongoMapper) GetNotificationCount(ctx context.Context, userId string) (int64, error) {
-	key := NotificationCountKey + userId
+	data, err := m.GetReadState(ctx, userId)
+	if err != nil {
+		return 0, err
+	}
+	return data.Read, nil
+}
+
+// GetReadState 用户还没有已读记录时创建一条空记录
+func (m MongoMapper) GetReadState(ctx context.Context, userId string) (*NotificationCount, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetReadState", time.Now())
 	var data *NotificationCount
-	uid, _ := primitive.ObjectIDFromHex(userId)
-	err := m.conn.FindOne(ctx, key, &data, bson.M{"_id": uid})
+	uid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, consts.ErrInvalidObjectId
+	}
+	key := cacheKey(ctx, userId)
+	// 先单独查一次缓存以统计命中率，未命中时 FindOne 会回源并写入缓存
+	if err = m.conn.GetCache(key, &data); err == nil {
+		metrics.NotificationCountCache.Inc("hit")
+		return data, nil
+	}
+	metrics.NotificationCountCache.Inc("miss")
+	err = m.conn.FindOne(ctx, key, &data, countFilter(ctx, uid))
 	switch {
 	case errors.Is(err, monc.ErrNotFound):
-		return 0, consts.ErrNotFound
+		return &NotificationCount{ID: uid}, m.CreateNotificationCount(ctx, &NotificationCount{ID: uid})
 	case err == nil:
-		return data.Read, err
+		return data, nil
 	default:
-		return 0, err
+		return nil, err
+	}
+}
+
+// UpdateReadAt 只会把已读时间往后推，返回是否推进了已读时间
+func (m MongoMapper) UpdateReadAt(ctx context.Context, userId string, readAt time.Time) (bool, error) {
+	defer metrics.ObserveMongo(CollectionName, "UpdateReadAt", time.Now())
+	uid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return false, consts.ErrInvalidObjectId
+	}
+	res, err := m.conn.UpdateOne(ctx, cacheKey(ctx, userId), countFilter(ctx, uid),
+		bson.M{"$max": bson.M{consts.ReadAt: readAt}}, options.Update().SetUpsert(true))
+	if err != nil {
+		return false, err
 	}
+	return res.ModifiedCount > 0 || res.UpsertedCount > 0, nil
 }
 
 func (m MongoMapper) UpdateNotificationCount(ctx context.Context, data *NotificationCount) error {
-	key := NotificationCountKey + data.ID.Hex()
-	_, err := m.conn.UpdateOne(ctx, key, bson.M{consts.ID: data.ID}, bson.M{"$set": data})
+	defer metrics.ObserveMongo(CollectionName, "UpdateNotificationCount", time.Now())
+	_, err := m.conn.UpdateOne(ctx, cacheKey(ctx, data.ID.Hex()), countFilter(ctx, data.ID),
+		bson.M{"$set": bson.M{consts.Read: data.Read}}, options.Update().SetUpsert(true))
 	return err
 }
 
+// countFilter 默认应用按 _id 查找，其他应用按 userId、appId 查找，upsert 时会一并写入这两个字段
+func countFilter(ctx context.Context, uid primitive.ObjectID) bson.M {
+	appId := identity.FromContext(ctx).AppId
+	if appId == "" {
+		return bson.M{consts.ID: uid}
+	}
+	return bson.M{consts.UserId: uid, consts.AppId: appId}
+}
+
+func cacheKey(ctx context.Context, userId string) string {
+	return NotificationCountKey + identity.CacheKeyPrefix(ctx) + userId
+}
+
+// EnsureNotificationCounts 为缺少已读记录的用户批量创建记录，返回新创建的记录数
+func (m MongoMapper) EnsureNotificationCounts(ctx context.Context, userIds []string) (int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "EnsureNotificationCounts", time.Now())
+	var total int64
+	for _, chunk := range lo.Chunk(userIds, backfillBatchSize) {
+		var (
+			models []mongo.WriteModel
+			keys   []string
+		)
+		for _, userId := range chunk {
+			uid, err := primitive.ObjectIDFromHex(userId)
+			if err != nil {
+				continue
+			}
+			models = append(models, mongo.NewUpdateOneModel().
+				SetFilter(countFilter(ctx, uid)).
+				SetUpdate(bson.M{"$setOnInsert": bson.M{consts.Read: int64(0)}}).
+				SetUpsert(true))
+			keys = append(keys, cacheKey(ctx, userId))
+		}
+		if len(models) == 0 {
+			continue
+		}
+		res, err := m.conn.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return total, err
+		}
+		total += res.UpsertedCount
+		// 清除之前缓存的“不存在”结果
+		if err = m.conn.DelCache(ctx, keys...); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// CountUsers 当前应用中有已读记录的用户数，用户第一次查看通知时就会创建记录，可以视为全站广播的接收人数
+func (m MongoMapper) CountUsers(ctx context.Context) (int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "CountUsers", time.Now())
+	filter := bson.M{consts.AppId: bson.M{"$exists": false}}
+	if appId := identity.FromContext(ctx).AppId; appId != "" {
+		filter = bson.M{consts.AppId: appId}
+	}
+	return m.conn.CountDocuments(ctx, filter)
+}
+
 func NewNotificationCountModel(config *config.Config) INotificationCountMongoMapper {
 	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	// 非默认应用的记录按 userId、appId 唯一，默认应用的记录以 _id 区分，不带这两个字段，不纳入索引；
+	// 已有重复数据时建索引会失败，只记录日志不影响启动
+	if _, err := conn.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: consts.UserId, Value: 1}, {Key: consts.AppId, Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{consts.AppId: bson.M{"$exists": true}}),
+	}); err != nil {
+		log.Error("[NotificationCount] create unique index failed, err=%v", err)
+	}
 	return &MongoMapper{
 		conn: conn,
 	}
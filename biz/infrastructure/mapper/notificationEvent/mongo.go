@@ -0,0 +1,138 @@
+package notificationEvent
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName   = "notificationEvent"
+	duplicateKeyCode = 11000
+)
+
+var _ INotificationEventMongoMapper = (*MongoMapper)(nil)
+
+type (
+	INotificationEventMongoMapper interface {
+		Record(ctx context.Context, data []*NotificationEvent) error
+		CountByType(ctx context.Context, from time.Time, to time.Time) ([]*EventCount, error)
+	}
+	// NotificationEvent 用户对通知的一次互动，同一用户对同一通知的同类事件只记录第一次，
+	// NotificationCreateAt 为通知的创建时间，互动按它计入通知创建当天的统计
+	NotificationEvent struct {
+		ID                   primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		NotificationId       string             `bson:"notificationId,omitempty" json:"notificationId,omitempty"`
+		UserId               string             `bson:"userId,omitempty" json:"userId,omitempty"`
+		Type                 int64              `bson:"type,omitempty" json:"type,omitempty"`
+		TargetType           int64              `bson:"targetType,omitempty" json:"targetType,omitempty"`
+		Event                string             `bson:"event,omitempty" json:"event,omitempty"`
+		NotificationCreateAt time.Time          `bson:"notificationCreateAt,omitempty" json:"notificationCreateAt,omitempty"`
+		AppId                string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		CreateAt             time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+	}
+	EventCount struct {
+		ID struct {
+			Type       int64  `bson:"type"`
+			TargetType int64  `bson:"targetType"`
+			Event      string `bson:"event"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+func (m *MongoMapper) Record(ctx context.Context, data []*NotificationEvent) error {
+	defer metrics.ObserveMongo(CollectionName, "Record", time.Now())
+	if len(data) == 0 {
+		return nil
+	}
+	now := time.Now()
+	models := make([]mongo.WriteModel, 0, len(data))
+	for _, e := range data {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(identity.Scope(ctx, bson.M{
+				consts.NotificationId: e.NotificationId,
+				consts.UserId:         e.UserId,
+				consts.Event:          e.Event,
+			})).
+			SetUpdate(bson.M{"$setOnInsert": bson.M{
+				consts.Type:                 e.Type,
+				consts.TargetType:           e.TargetType,
+				consts.NotificationCreateAt: e.NotificationCreateAt,
+				consts.CreateAt:             now,
+			}}).
+			SetUpsert(true))
+	}
+	_, err := m.conn.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if onlyDuplicateKey(err) {
+		// 多个设备同时打开同一条通知时后到的 upsert 会违反唯一索引，此时事件已经记录
+		return nil
+	}
+	return err
+}
+
+func onlyDuplicateKey(err error) bool {
+	var e mongo.BulkWriteException
+	if !errors.As(err, &e) || e.WriteConcernError != nil || len(e.WriteErrors) == 0 {
+		return false
+	}
+	return lo.EveryBy(e.WriteErrors, func(we mongo.BulkWriteError) bool {
+		return we.Code == duplicateKeyCode
+	})
+}
+
+// CountByType 统计当前应用 [from, to) 内创建的通知收到的互动次数，按通知类型、目标类型分组
+func (m *MongoMapper) CountByType(ctx context.Context, from time.Time, to time.Time) ([]*EventCount, error) {
+	defer metrics.ObserveMongo(CollectionName, "CountByType", time.Now())
+	var data []*EventCount
+	if err := m.conn.Aggregate(ctx, &data, []bson.M{
+		{"$match": identity.Scope(ctx, bson.M{consts.NotificationCreateAt: bson.M{"$gte": from, "$lt": to}})},
+		{"$group": bson.M{
+			consts.ID: bson.M{
+				consts.Type:       "$" + consts.Type,
+				consts.TargetType: "$" + consts.TargetType,
+				consts.Event:      "$" + consts.Event,
+			},
+			"count": bson.M{"$sum": 1},
+		}},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func NewNotificationEventModel(config *config.Config) INotificationEventMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	// 同一用户对同一通知的同类事件只能有一条记录，已有重复数据时建索引会失败，只记录日志不影响启动
+	if _, err := conn.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{
+			{Key: consts.AppId, Value: 1},
+			{Key: consts.NotificationId, Value: 1},
+			{Key: consts.UserId, Value: 1},
+			{Key: consts.Event, Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}); err != nil {
+		log.Error("[NotificationEvent] create unique index failed, err=%v", err)
+	}
+	return &MongoMapper{
+		conn: conn,
+	}
+}
@@ -0,0 +1,89 @@
+package notificationStat
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName = "notificationStat"
+)
+
+var _ INotificationStatMongoMapper = (*MongoMapper)(nil)
+
+type (
+	INotificationStatMongoMapper interface {
+		Upsert(ctx context.Context, data *NotificationStat) error
+		GetStats(ctx context.Context, fromDate string, toDate string, onlyType *int64) ([]*NotificationStat, error)
+	}
+	// NotificationStat 某一天某种通知的发送与互动汇总，Delivered 为送达人次，全站广播按接收人数计算
+	NotificationStat struct {
+		ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		Date       string             `bson:"date" json:"date"`
+		Type       int64              `bson:"type" json:"type"`
+		TargetType int64              `bson:"targetType" json:"targetType"`
+		Created    int64              `bson:"created" json:"created"`
+		Delivered  int64              `bson:"delivered" json:"delivered"`
+		Opened     int64              `bson:"opened" json:"opened"`
+		Clicked    int64              `bson:"clicked" json:"clicked"`
+		AppId      string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		UpdateAt   time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+// Upsert 以应用、日期、通知类型和目标类型为键覆盖写入汇总结果，重复汇总同一天是幂等的
+func (m *MongoMapper) Upsert(ctx context.Context, data *NotificationStat) error {
+	defer metrics.ObserveMongo(CollectionName, "Upsert", time.Now())
+	data.UpdateAt = time.Now()
+	_, err := m.conn.UpdateOneNoCache(ctx, identity.Scope(ctx, bson.M{
+		consts.Date:       data.Date,
+		consts.Type:       data.Type,
+		consts.TargetType: data.TargetType,
+	}), bson.M{
+		"$set": bson.M{
+			"created":       data.Created,
+			"delivered":     data.Delivered,
+			"opened":        data.Opened,
+			"clicked":       data.Clicked,
+			consts.UpdateAt: data.UpdateAt,
+		},
+	}, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetStats 查询 [fromDate, toDate] 内的汇总结果，按日期升序
+func (m *MongoMapper) GetStats(ctx context.Context, fromDate string, toDate string, onlyType *int64) ([]*NotificationStat, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetStats", time.Now())
+	var data []*NotificationStat
+	filter := identity.Scope(ctx, bson.M{consts.Date: bson.M{"$gte": fromDate, "$lte": toDate}})
+	if onlyType != nil {
+		filter[consts.Type] = *onlyType
+	}
+	if err := m.conn.Find(ctx, &data, filter, &options.FindOptions{
+		Sort: bson.D{{Key: consts.Date, Value: 1}, {Key: consts.Type, Value: 1}, {Key: consts.TargetType, Value: 1}},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func NewNotificationStatModel(config *config.Config) INotificationStatMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	return &MongoMapper{
+		conn: conn,
+	}
+}
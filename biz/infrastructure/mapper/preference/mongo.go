@@ -0,0 +1,202 @@
+package preference
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName      = "preference"
+	PreferenceKeyPrefix = "cache:preference:"
+)
+
+var _ IPreferenceMongoMapper = (*MongoMapper)(nil)
+
+type (
+	IPreferenceMongoMapper interface {
+		GetPreference(ctx context.Context, userId string) (*Preference, error)
+		UpsertPreference(ctx context.Context, data *Preference) error
+		GetDigestDuePreferences(ctx context.Context, frequency int64, before time.Time) ([]*Preference, error)
+		ClaimDigest(ctx context.Context, data *Preference, now time.Time, lease time.Duration) (bool, error)
+		CommitDigest(ctx context.Context, data *Preference, now time.Time) error
+		ReleaseDigest(ctx context.Context, data *Preference) error
+	}
+	// Preference 与已读记录相同，默认应用的记录以用户 id 为 _id，其他应用的记录按 userId、appId 区分
+	Preference struct {
+		ID               primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		UserId           primitive.ObjectID `bson:"userId,omitempty" json:"userId,omitempty"`
+		AppId            string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		Email            string             `bson:"email,omitempty" json:"email,omitempty"`
+		DigestFrequency  int64              `bson:"digestFrequency,omitempty" json:"digestFrequency,omitempty"`
+		LastDigestAt     time.Time          `bson:"lastDigestAt,omitempty" json:"lastDigestAt,omitempty"`
+		DigestLeaseUntil time.Time          `bson:"digestLeaseUntil,omitempty" json:"digestLeaseUntil,omitempty"`
+		TimeZone         string             `bson:"timeZone,omitempty" json:"timeZone,omitempty"`
+		QuietStart       string             `bson:"quietStart,omitempty" json:"quietStart,omitempty"`
+		QuietEnd         string             `bson:"quietEnd,omitempty" json:"quietEnd,omitempty"`
+		CreateAt         time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+		UpdateAt         time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+// GetUserId 返回偏好设置所属的用户
+func (p *Preference) GetUserId() string {
+	if !p.UserId.IsZero() {
+		return p.UserId.Hex()
+	}
+	return p.ID.Hex()
+}
+
+// preferenceFilter 默认应用按 _id 查找，其他应用按 userId、appId 查找，upsert 时会一并写入这两个字段
+func preferenceFilter(ctx context.Context, uid primitive.ObjectID) bson.M {
+	appId := identity.FromContext(ctx).AppId
+	if appId == "" {
+		return bson.M{consts.ID: uid}
+	}
+	return bson.M{consts.UserId: uid, consts.AppId: appId}
+}
+
+// docFilter 按记录本身的 _id 更新由 GetDigestDuePreferences 查出的记录
+func docFilter(ctx context.Context, data *Preference) bson.M {
+	return identity.Scope(ctx, bson.M{consts.ID: data.ID})
+}
+
+func cacheKey(ctx context.Context, userId string) string {
+	return PreferenceKeyPrefix + identity.CacheKeyPrefix(ctx) + userId
+}
+
+func (m *MongoMapper) GetPreference(ctx context.Context, userId string) (*Preference, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetPreference", time.Now())
+	uid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, consts.ErrInvalidObjectId
+	}
+	var data Preference
+	err = m.conn.FindOne(ctx, cacheKey(ctx, userId), &data, preferenceFilter(ctx, uid))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return nil, consts.ErrNotFound
+	case err == nil:
+		return &data, nil
+	default:
+		return nil, err
+	}
+}
+
+// UpsertPreference 整体覆盖用户可编辑的字段，零值表示清除该项设置，摘要发送状态不受影响，data.ID 为用户 id
+func (m *MongoMapper) UpsertPreference(ctx context.Context, data *Preference) error {
+	defer metrics.ObserveMongo(CollectionName, "UpsertPreference", time.Now())
+	now := time.Now()
+	set := bson.M{consts.UpdateAt: now}
+	unset := bson.M{}
+	for field, value := range map[string]any{
+		consts.Email:           data.Email,
+		consts.DigestFrequency: data.DigestFrequency,
+		consts.TimeZone:        data.TimeZone,
+		consts.QuietStart:      data.QuietStart,
+		consts.QuietEnd:        data.QuietEnd,
+	} {
+		if value == "" || value == int64(0) {
+			unset[field] = ""
+		} else {
+			set[field] = value
+		}
+	}
+	update := bson.M{
+		"$set":         set,
+		"$setOnInsert": bson.M{consts.CreateAt: now},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	_, err := m.conn.UpdateOne(ctx, cacheKey(ctx, data.ID.Hex()), preferenceFilter(ctx, data.ID), update, options.Update().SetUpsert(true))
+	return err
+}
+
+// GetDigestDuePreferences 查询订阅了指定频率摘要，且上次发送早于 before 的用户
+func (m *MongoMapper) GetDigestDuePreferences(ctx context.Context, frequency int64, before time.Time) ([]*Preference, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetDigestDuePreferences", time.Now())
+	var data []*Preference
+	if err := m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{
+		consts.DigestFrequency: frequency,
+		consts.Email:           bson.M{"$exists": true, "$ne": ""},
+		"$or": []bson.M{
+			{consts.LastDigestAt: bson.M{"$exists": false}},
+			{consts.LastDigestAt: bson.M{"$lte": before}},
+		},
+	})); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// ClaimDigest 以上次发送时间作为乐观锁加上租约，多实例时只有一个实例能领取成功，
+// 上次发送时间要等 CommitDigest 才会推进
+func (m *MongoMapper) ClaimDigest(ctx context.Context, data *Preference, now time.Time, lease time.Duration) (bool, error) {
+	defer metrics.ObserveMongo(CollectionName, "ClaimDigest", time.Now())
+	filter := docFilter(ctx, data)
+	filter["$or"] = []bson.M{
+		{consts.DigestLeaseUntil: bson.M{"$exists": false}},
+		{consts.DigestLeaseUntil: bson.M{"$lte": now}},
+	}
+	if data.LastDigestAt.IsZero() {
+		filter[consts.LastDigestAt] = bson.M{"$exists": false}
+	} else {
+		filter[consts.LastDigestAt] = data.LastDigestAt
+	}
+	res, err := m.conn.UpdateOne(ctx, cacheKey(ctx, data.GetUserId()), filter, bson.M{"$set": bson.M{consts.DigestLeaseUntil: now.Add(lease)}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// CommitDigest 摘要发送成功后推进上次发送时间并释放租约
+func (m *MongoMapper) CommitDigest(ctx context.Context, data *Preference, now time.Time) error {
+	defer metrics.ObserveMongo(CollectionName, "CommitDigest", time.Now())
+	_, err := m.conn.UpdateOne(ctx, cacheKey(ctx, data.GetUserId()), docFilter(ctx, data), bson.M{
+		"$set":   bson.M{consts.LastDigestAt: now},
+		"$unset": bson.M{consts.DigestLeaseUntil: ""},
+	})
+	return err
+}
+
+// ReleaseDigest 摘要发送失败时释放租约，下一轮任务会重新发送
+func (m *MongoMapper) ReleaseDigest(ctx context.Context, data *Preference) error {
+	defer metrics.ObserveMongo(CollectionName, "ReleaseDigest", time.Now())
+	_, err := m.conn.UpdateOne(ctx, cacheKey(ctx, data.GetUserId()), docFilter(ctx, data), bson.M{
+		"$unset": bson.M{consts.DigestLeaseUntil: ""},
+	})
+	return err
+}
+
+func NewPreferenceModel(config *config.Config) IPreferenceMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	// 非默认应用的记录按 userId、appId 唯一，已有重复数据时建索引会失败，只记录日志不影响启动
+	if _, err := conn.Indexes().CreateOne(context.Background(), mongo.IndexModel{
+		Keys: bson.D{{Key: consts.UserId, Value: 1}, {Key: consts.AppId, Value: 1}},
+		Options: options.Index().SetUnique(true).
+			SetPartialFilterExpression(bson.M{consts.AppId: bson.M{"$exists": true}}),
+	}); err != nil {
+		log.Error("[Preference] create unique index failed, err=%v", err)
+	}
+	return &MongoMapper{
+		conn: conn,
+	}
+}
@@ -1,7 +1,10 @@
 package slider
 
 import (
+	"context"
+
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -15,11 +18,27 @@ type MongoFilter struct {
 	*FilterOptions
 }
 
-func MakeBsonFilter(options *FilterOptions) bson.M {
-	return (&MongoFilter{
+// MakeBsonFilter 生成查询条件，并按 ctx 中的应用隔离数据
+func MakeBsonFilter(ctx context.Context, options *FilterOptions) bson.M {
+	f := &MongoFilter{
 		m:             bson.M{},
 		FilterOptions: options,
-	}).toBson()
+	}
+	f.toBson()
+	f.CheckAppId(ctx)
+	return f.m
+}
+
+// IsEmptyFilter 除应用隔离外没有任何查询条件
+func IsEmptyFilter(options *FilterOptions) bool {
+	return len((&MongoFilter{
+		m:             bson.M{},
+		FilterOptions: options,
+	}).toBson()) == 0
+}
+
+func (f *MongoFilter) CheckAppId(ctx context.Context) {
+	identity.Scope(ctx, f.m)
 }
 
 func (f *MongoFilter) toBson() bson.M {
@@ -2,6 +2,7 @@ package slider
 
 import (
 	"context"
+	"errors"
 	"github.com/CloudStriver/go-pkg/utils/pagination"
 	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
 	"github.com/samber/lo"
@@ -15,6 +16,8 @@ import (
 
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
 )
 
 const (
@@ -34,37 +37,74 @@ type (
 		GetSlidersAndCount(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*Slider, int64, error)
 		UpdateOne(ctx context.Context, data *Slider) error
 		DeleteOne(ctx context.Context, id string) error
+		FindOne(ctx context.Context, id string) (*Slider, error)
 	}
 	Slider struct {
-		ID       primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-		ImageUrl string             `bson:"imageUrl,omitempty" json:"imageUrl,omitempty"`
-		LinkUrl  string             `bson:"linkUrl,omitempty" json:"linkUrl,omitempty"`
-		IsPublic int64              `bson:"isPublic,omitempty" json:"isPublic,omitempty"`
-		UpdateAt time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
-		CreateAt time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+		ID         primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		ImageUrl   string             `bson:"imageUrl,omitempty" json:"imageUrl,omitempty"`
+		LinkUrl    string             `bson:"linkUrl,omitempty" json:"linkUrl,omitempty"`
+		IsPublic   int64              `bson:"isPublic,omitempty" json:"isPublic,omitempty"`
+		NeedReview bool               `bson:"needReview,omitempty" json:"needReview,omitempty"`
+		AppId      string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		UpdateAt   time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+		CreateAt   time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
 	}
 	MongoMapper struct {
 		conn *monc.Model
 	}
 )
 
+// idFilter 按 id 查询单个轮播图，同时按应用隔离
+func idFilter(ctx context.Context, oid primitive.ObjectID) bson.M {
+	return identity.Scope(ctx, bson.M{consts.ID: oid})
+}
+
+func cacheKey(ctx context.Context, id string) string {
+	return prefixSliderCacheKey + identity.CacheKeyPrefix(ctx) + id
+}
+
 func (m *MongoMapper) UpdateOne(ctx context.Context, data *Slider) error {
+	defer metrics.ObserveMongo(CollectionName, "UpdateOne", time.Now())
 	data.UpdateAt = time.Now()
-	key := prefixSliderCacheKey + data.ID.Hex()
-	_, err := m.conn.UpdateByID(ctx, key, data.ID, bson.M{"$set": data})
+	data.AppId = identity.FromContext(ctx).AppId
+	update := bson.M{"$set": data}
+	if !data.NeedReview {
+		// omitempty 会省略 false，需要显式清除，否则被标记待审核的轮播图修改后无法解除标记
+		update["$unset"] = bson.M{consts.NeedReview: ""}
+	}
+	_, err := m.conn.UpdateOne(ctx, cacheKey(ctx, data.ID.Hex()), idFilter(ctx, data.ID), update)
 	return err
 }
 
 func (m *MongoMapper) DeleteOne(ctx context.Context, id string) error {
+	defer metrics.ObserveMongo(CollectionName, "DeleteOne", time.Now())
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return consts.ErrInvalidObjectId
 	}
-	key := prefixSliderCacheKey + id
-	_, err = m.conn.DeleteOne(ctx, key, bson.M{consts.ID: oid})
+	_, err = m.conn.DeleteOne(ctx, cacheKey(ctx, id), idFilter(ctx, oid))
 	return err
 }
+func (m *MongoMapper) FindOne(ctx context.Context, id string) (*Slider, error) {
+	defer metrics.ObserveMongo(CollectionName, "FindOne", time.Now())
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, consts.ErrInvalidObjectId
+	}
+	var data Slider
+	err = m.conn.FindOne(ctx, cacheKey(ctx, id), &data, idFilter(ctx, oid))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return nil, consts.ErrNotFound
+	case err == nil:
+		return &data, nil
+	default:
+		return nil, err
+	}
+}
+
 func (m *MongoMapper) GetSlidersAndCount(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*Slider, int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetSlidersAndCount", time.Now())
 	var (
 		data       []*Slider
 		count      int64
@@ -72,7 +112,7 @@ func (m *MongoMapper) GetSlidersAndCount(ctx context.Context, fopts *FilterOptio
 	)
 	p := mongop.NewMongoPaginator(pagination.NewRawStore(sorter), popts)
 
-	filter := MakeBsonFilter(fopts)
+	filter := MakeBsonFilter(ctx, fopts)
 	sort, err := p.MakeSortOptions(ctx, filter)
 	if err != nil {
 		return nil, 0, err
@@ -110,20 +150,22 @@ func (m *MongoMapper) GetSlidersAndCount(ctx context.Context, fopts *FilterOptio
 	return data, count, nil
 }
 func (m *MongoMapper) InsertOne(ctx context.Context, data *Slider) error {
+	defer metrics.ObserveMongo(CollectionName, "InsertOne", time.Now())
 	if data.ID.IsZero() {
 		data.ID = primitive.NewObjectID()
 	}
 	data.CreateAt = time.Now()
 	data.UpdateAt = time.Now()
-	key := prefixSliderCacheKey + data.ID.Hex()
-	_, err := m.conn.InsertOne(ctx, key, data)
+	data.AppId = identity.FromContext(ctx).AppId
+	_, err := m.conn.InsertOne(ctx, cacheKey(ctx, data.ID.Hex()), data)
 	return err
 }
 func (m *MongoMapper) GetSliders(ctx context.Context, fopts *FilterOptions, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*Slider, int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetSliders", time.Now())
 	var data []*Slider
 	p := mongop.NewMongoPaginator(pagination.NewRawStore(sorter), popts)
 
-	filter := MakeBsonFilter(fopts)
+	filter := MakeBsonFilter(ctx, fopts)
 	sort, err := p.MakeSortOptions(ctx, filter)
 	if err != nil {
 		return nil, 0, err
@@ -159,7 +201,8 @@ func (m *MongoMapper) GetSliders(ctx context.Context, fopts *FilterOptions, popt
 }
 
 func (m *MongoMapper) ReadSliders(ctx context.Context, fopts *FilterOptions) error {
-	filter := MakeBsonFilter(fopts)
+	defer metrics.ObserveMongo(CollectionName, "ReadSliders", time.Now())
+	filter := MakeBsonFilter(ctx, fopts)
 	if _, err := m.conn.UpdateManyNoCache(ctx, filter, bson.M{"$set": bson.M{consts.IsRead: true, consts.UpdateAt: time.Now()}}); err != nil {
 		return err
 	}
@@ -168,27 +211,29 @@ func (m *MongoMapper) ReadSliders(ctx context.Context, fopts *FilterOptions) err
 
 // CleanSlider 清除未读消息
 func (m *MongoMapper) CleanSlider(ctx context.Context, userId string) error {
-	filter := bson.M{
+	defer metrics.ObserveMongo(CollectionName, "CleanSlider", time.Now())
+	filter := identity.Scope(ctx, bson.M{
 		consts.TargetUserId: userId,
 		consts.IsRead:       bson.M{"$exists": false},
-	}
+	})
 	_, err := m.conn.UpdateManyNoCache(ctx, filter, bson.M{"$set": bson.M{consts.IsRead: true, consts.UpdateAt: time.Now()}})
 	return err
 }
 
 func (m *MongoMapper) ReadSlider(ctx context.Context, id string) error {
+	defer metrics.ObserveMongo(CollectionName, "ReadSlider", time.Now())
 	oid, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
 		return consts.ErrInvalidObjectId
 	}
 
-	key := prefixSliderCacheKey + id
-	_, err = m.conn.UpdateByID(ctx, key, oid, bson.M{"$set": bson.M{consts.IsRead: true, consts.UpdateAt: time.Now()}})
+	_, err = m.conn.UpdateOne(ctx, cacheKey(ctx, id), idFilter(ctx, oid), bson.M{"$set": bson.M{consts.IsRead: true, consts.UpdateAt: time.Now()}})
 	return err
 }
 
 func (m *MongoMapper) Count(ctx context.Context, fopts *FilterOptions) (int64, error) {
-	f := MakeBsonFilter(fopts)
+	defer metrics.ObserveMongo(CollectionName, "Count", time.Now())
+	f := MakeBsonFilter(ctx, fopts)
 	return m.conn.CountDocuments(ctx, f)
 }
 
@@ -0,0 +1,86 @@
+package tombstone
+
+import (
+	"context"
+	"time"
+
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName = "notificationTombstone"
+)
+
+var _ ITombstoneMongoMapper = (*MongoMapper)(nil)
+
+type (
+	// ITombstoneMongoMapper 记录已删除的通知，供客户端增量同步时移除本地缓存。记录的 AppId 取自被删除的通知，
+	// 后台任务跨应用删除通知时也能归属到正确的应用
+	ITombstoneMongoMapper interface {
+		InsertMany(ctx context.Context, data []*Tombstone) error
+		GetTombstones(ctx context.Context, userIds []string, after time.Time) ([]*Tombstone, error)
+		DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+	}
+	Tombstone struct {
+		ID             primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		NotificationId string             `bson:"notificationId,omitempty" json:"notificationId,omitempty"`
+		TargetUserId   string             `bson:"targetUserId,omitempty" json:"targetUserId,omitempty"`
+		AppId          string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		CreateAt       time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+func (m *MongoMapper) InsertMany(ctx context.Context, data []*Tombstone) error {
+	defer metrics.ObserveMongo(CollectionName, "InsertMany", time.Now())
+	if len(data) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, t := range data {
+		if t.ID.IsZero() {
+			t.ID = primitive.NewObjectID()
+		}
+		t.CreateAt = now
+	}
+	_, err := m.conn.InsertMany(ctx, lo.ToAnySlice(data))
+	return err
+}
+
+func (m *MongoMapper) GetTombstones(ctx context.Context, userIds []string, after time.Time) ([]*Tombstone, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetTombstones", time.Now())
+	var data []*Tombstone
+	if err := m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{
+		consts.TargetUserId: bson.M{"$in": userIds},
+		consts.CreateAt:     bson.M{"$gt": after},
+	}), &options.FindOptions{
+		Sort: bson.M{consts.CreateAt: 1},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (m *MongoMapper) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	defer metrics.ObserveMongo(CollectionName, "DeleteBefore", time.Now())
+	return m.conn.DeleteMany(ctx, identity.Scope(ctx, bson.M{consts.CreateAt: bson.M{"$lt": before}}))
+}
+
+func NewTombstoneModel(config *config.Config) ITombstoneMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	return &MongoMapper{
+		conn: conn,
+	}
+}
@@ -0,0 +1,117 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName = "webhook"
+)
+
+var _ IWebhookMongoMapper = (*MongoMapper)(nil)
+
+type (
+	IWebhookMongoMapper interface {
+		InsertOne(ctx context.Context, data *Webhook) error
+		UpdateOne(ctx context.Context, data *Webhook) error
+		DeleteOne(ctx context.Context, id string) error
+		FindOne(ctx context.Context, id string) (*Webhook, error)
+		GetWebhooks(ctx context.Context) ([]*Webhook, error)
+	}
+	Webhook struct {
+		ID       primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		Url      string             `bson:"url,omitempty" json:"url,omitempty"`
+		Events   []string           `bson:"events,omitempty" json:"events,omitempty"`
+		Secret   string             `bson:"secret,omitempty" json:"-"`
+		AppId    string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		CreateAt time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+		UpdateAt time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+func (m *MongoMapper) InsertOne(ctx context.Context, data *Webhook) error {
+	defer metrics.ObserveMongo(CollectionName, "InsertOne", time.Now())
+	if data.ID.IsZero() {
+		data.ID = primitive.NewObjectID()
+	}
+	data.CreateAt = time.Now()
+	data.UpdateAt = time.Now()
+	data.AppId = identity.FromContext(ctx).AppId
+	_, err := m.conn.InsertOneNoCache(ctx, data)
+	return err
+}
+
+func (m *MongoMapper) UpdateOne(ctx context.Context, data *Webhook) error {
+	defer metrics.ObserveMongo(CollectionName, "UpdateOne", time.Now())
+	data.UpdateAt = time.Now()
+	res, err := m.conn.UpdateOneNoCache(ctx, identity.Scope(ctx, bson.M{consts.ID: data.ID}), bson.M{"$set": data})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return consts.ErrNotFound
+	}
+	return nil
+}
+
+func (m *MongoMapper) DeleteOne(ctx context.Context, id string) error {
+	defer metrics.ObserveMongo(CollectionName, "DeleteOne", time.Now())
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return consts.ErrInvalidObjectId
+	}
+	_, err = m.conn.DeleteOneNoCache(ctx, identity.Scope(ctx, bson.M{consts.ID: oid}))
+	return err
+}
+
+func (m *MongoMapper) FindOne(ctx context.Context, id string) (*Webhook, error) {
+	defer metrics.ObserveMongo(CollectionName, "FindOne", time.Now())
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, consts.ErrInvalidObjectId
+	}
+	var data Webhook
+	err = m.conn.FindOneNoCache(ctx, &data, identity.Scope(ctx, bson.M{consts.ID: oid}))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return nil, consts.ErrNotFound
+	case err == nil:
+		return &data, nil
+	default:
+		return nil, err
+	}
+}
+
+func (m *MongoMapper) GetWebhooks(ctx context.Context) ([]*Webhook, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetWebhooks", time.Now())
+	var data []*Webhook
+	if err := m.conn.Find(ctx, &data, identity.Scope(ctx, bson.M{}), &options.FindOptions{
+		Sort: bson.M{consts.ID: -1},
+	}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func NewWebhookModel(config *config.Config) IWebhookMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	return &MongoMapper{
+		conn: conn,
+	}
+}
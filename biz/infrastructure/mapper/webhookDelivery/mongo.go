@@ -0,0 +1,135 @@
+package webhookDelivery
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/pagination"
+	"github.com/CloudStriver/go-pkg/utils/pagination/mongop"
+	"github.com/samber/lo"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/zeromicro/go-zero/core/stores/monc"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/identity"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/metrics"
+)
+
+const (
+	CollectionName = "webhookDelivery"
+)
+
+var _ IWebhookDeliveryMongoMapper = (*MongoMapper)(nil)
+
+type (
+	IWebhookDeliveryMongoMapper interface {
+		InsertMany(ctx context.Context, data []*WebhookDelivery) error
+		ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*WebhookDelivery, error)
+		UpdateOne(ctx context.Context, data *WebhookDelivery) error
+		GetDeliveries(ctx context.Context, webhookId string, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*WebhookDelivery, error)
+	}
+	WebhookDelivery struct {
+		ID            primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+		WebhookId     string             `bson:"webhookId,omitempty" json:"webhookId,omitempty"`
+		Event         string             `bson:"event,omitempty" json:"event,omitempty"`
+		Payload       string             `bson:"payload,omitempty" json:"payload,omitempty"`
+		Status        int64              `bson:"status,omitempty" json:"status,omitempty"`
+		Attempts      int64              `bson:"attempts,omitempty" json:"attempts,omitempty"`
+		ResponseCode  int64              `bson:"responseCode,omitempty" json:"responseCode,omitempty"`
+		LastError     string             `bson:"lastError,omitempty" json:"lastError,omitempty"`
+		NextAttemptAt time.Time          `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+		CreateAt      time.Time          `bson:"createAt,omitempty" json:"createAt,omitempty"`
+		AppId         string             `bson:"appId,omitempty" json:"appId,omitempty"`
+		UpdateAt      time.Time          `bson:"updateAt,omitempty" json:"updateAt,omitempty"`
+	}
+	MongoMapper struct {
+		conn *monc.Model
+	}
+)
+
+func (m *MongoMapper) InsertMany(ctx context.Context, data []*WebhookDelivery) error {
+	defer metrics.ObserveMongo(CollectionName, "InsertMany", time.Now())
+	if len(data) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for _, d := range data {
+		if d.ID.IsZero() {
+			d.ID = primitive.NewObjectID()
+		}
+		d.CreateAt = now
+		d.UpdateAt = now
+		d.AppId = identity.FromContext(ctx).AppId
+	}
+	_, err := m.conn.InsertMany(ctx, lo.ToAnySlice(data))
+	return err
+}
+
+// ClaimDue 领取一条到期的待推送记录，并把下次推送时间推后 lease 防止被重复领取
+func (m *MongoMapper) ClaimDue(ctx context.Context, now time.Time, lease time.Duration) (*WebhookDelivery, error) {
+	defer metrics.ObserveMongo(CollectionName, "ClaimDue", time.Now())
+	var data WebhookDelivery
+	err := m.conn.FindOneAndUpdateNoCache(ctx, &data, identity.Scope(ctx, bson.M{
+		consts.Status:        consts.DeliveryStatusPending,
+		consts.NextAttemptAt: bson.M{"$lte": now},
+	}), bson.M{
+		"$set": bson.M{consts.NextAttemptAt: now.Add(lease), consts.UpdateAt: now},
+	}, options.FindOneAndUpdate().SetSort(bson.M{consts.NextAttemptAt: 1}).SetReturnDocument(options.After))
+	switch {
+	case errors.Is(err, monc.ErrNotFound):
+		return nil, consts.ErrNotFound
+	case err == nil:
+		return &data, nil
+	default:
+		return nil, err
+	}
+}
+
+func (m *MongoMapper) UpdateOne(ctx context.Context, data *WebhookDelivery) error {
+	defer metrics.ObserveMongo(CollectionName, "UpdateOne", time.Now())
+	data.UpdateAt = time.Now()
+	_, err := m.conn.UpdateOneNoCache(ctx, identity.Scope(ctx, bson.M{consts.ID: data.ID}), bson.M{"$set": data})
+	return err
+}
+
+func (m *MongoMapper) GetDeliveries(ctx context.Context, webhookId string, popts *pagination.PaginationOptions, sorter mongop.MongoCursor) ([]*WebhookDelivery, error) {
+	defer metrics.ObserveMongo(CollectionName, "GetDeliveries", time.Now())
+	var data []*WebhookDelivery
+	p := mongop.NewMongoPaginator(pagination.NewRawStore(sorter), popts)
+	filter := identity.Scope(ctx, bson.M{consts.WebhookId: webhookId})
+	sort, err := p.MakeSortOptions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = m.conn.Find(ctx, &data, filter, &options.FindOptions{
+		Sort:  sort,
+		Limit: popts.Limit,
+		Skip:  popts.Offset,
+	}); err != nil {
+		return nil, err
+	}
+	// 如果是反向查询，反转数据
+	if *popts.Backward {
+		lo.Reverse(data)
+	}
+	if len(data) > 0 {
+		err = p.StoreCursor(ctx, data[0], data[len(data)-1])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+func NewWebhookDeliveryModel(config *config.Config) IWebhookDeliveryMongoMapper {
+	conn := monc.MustNewModel(config.Mongo.URL, config.Mongo.DB, CollectionName, config.CacheConf)
+	return &MongoMapper{
+		conn: conn,
+	}
+}
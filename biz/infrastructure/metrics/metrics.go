@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/zeromicro/go-zero/core/metric"
+)
+
+// 指标通过 ServiceConf.DevServer 的 /metrics 暴露，DevServer 未开启时不会记录
+const namespace = "cloudmind_system"
+
+var (
+	// NotificationRateLimited 因频率限制被丢弃或合并的通知数
+	NotificationRateLimited = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "notification",
+		Name:      "rate_limited_total",
+		Help:      "notifications dropped or merged by the anti-spam rate limiter",
+		Labels:    []string{"type", "action"},
+	})
+	// NotificationCreated 成功创建的通知数
+	NotificationCreated = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "notification",
+		Name:      "created_total",
+		Help:      "notifications created",
+		Labels:    []string{"type"},
+	})
+	// NotificationCountCache 已读数缓存的命中情况，result 为 hit 或 miss
+	NotificationCountCache = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "notification_count",
+		Name:      "cache_total",
+		Help:      "read counter cache lookups",
+		Labels:    []string{"result"},
+	})
+	// SliderRead 轮播图列表的读取次数，placement 为请求的轮播图类型，未指定时为 all
+	SliderRead = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: namespace,
+		Subsystem: "slider",
+		Name:      "read_total",
+		Help:      "slider list reads",
+		Labels:    []string{"placement"},
+	})
+	// MongoDuration mapper 方法的耗时（毫秒）
+	MongoDuration = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: namespace,
+		Subsystem: "mongo",
+		Name:      "duration_ms",
+		Help:      "mongo mapper method duration in milliseconds",
+		Labels:    []string{"mapper", "method"},
+		Buckets:   []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000, 2500},
+	})
+	// JobDuration 后台任务单次运行的耗时（毫秒）
+	JobDuration = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: namespace,
+		Subsystem: "job",
+		Name:      "duration_ms",
+		Help:      "background job run duration in milliseconds",
+		Labels:    []string{"job"},
+		Buckets:   []float64{10, 50, 100, 500, 1000, 5000, 10000, 30000, 60000, 300000},
+	})
+)
+
+// ObserveMongo 供 defer 使用：defer metrics.ObserveMongo(CollectionName, "FindOne", time.Now())
+func ObserveMongo(mapper string, method string, start time.Time) {
+	MongoDuration.Observe(time.Since(start).Milliseconds(), mapper, method)
+}
+
+// ObserveJob 供 defer 使用：defer metrics.ObserveJob("retention", time.Now())
+func ObserveJob(job string, start time.Time) {
+	JobDuration.Observe(time.Since(start).Milliseconds(), job)
+}
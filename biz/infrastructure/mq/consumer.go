@@ -0,0 +1,36 @@
+package mq
+
+import (
+	"context"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/consts"
+)
+
+type (
+	// Event 上游服务发布的领域事件
+	Event struct {
+		Type            string            `json:"type"`
+		SourceUserId    string            `json:"sourceUserId,omitempty"`
+		TargetUserId    string            `json:"targetUserId,omitempty"`
+		SourceContentId string            `json:"sourceContentId,omitempty"`
+		Extra           map[string]string `json:"extra,omitempty"`
+		// AppId 事件所属的应用，为空时属于默认应用
+		AppId string `json:"appId,omitempty"`
+	}
+	Handler func(ctx context.Context, event *Event) error
+	// Consumer 领域事件消费者，Start 会阻塞直到 Stop 被调用
+	Consumer interface {
+		Start(handler Handler)
+		Stop()
+	}
+)
+
+func NewConsumer(config *config.Config) (Consumer, error) {
+	switch config.EventQueue.Driver {
+	case consts.KafkaQueueDriver:
+		return newKafkaConsumer(config)
+	default:
+		return NewMemoryConsumer(config.EventQueue.Buffer), nil
+	}
+}
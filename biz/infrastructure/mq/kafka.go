@@ -0,0 +1,106 @@
+package mq
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+	"github.com/zeromicro/go-queue/kq"
+	"github.com/zeromicro/go-zero/core/queue"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+)
+
+var _ Consumer = (*KafkaConsumer)(nil)
+
+// KafkaConsumer 基于 go-queue kq 的 Kafka 事件消费者，事件处理成功后才提交位移，
+// 处理失败时按指数退避重试，直到成功或者消费者停止，停止时未提交的事件在下次启动后重新消费
+type KafkaConsumer struct {
+	queue      queue.MessageQueue
+	handler    Handler
+	backoff    time.Duration
+	maxBackoff time.Duration
+	done       chan struct{}
+	mu         sync.Mutex
+	started    bool
+	stopped    bool
+}
+
+func newKafkaConsumer(config *config.Config) (Consumer, error) {
+	k := config.EventQueue.Kafka
+	c := &KafkaConsumer{
+		backoff:    k.Backoff,
+		maxBackoff: k.MaxBackoff,
+		done:       make(chan struct{}),
+	}
+	q, err := kq.NewQueue(kq.KqConf{
+		ServiceConf: config.ServiceConf,
+		Brokers:     k.Brokers,
+		Group:       k.Group,
+		Topic:       k.Topic,
+		Offset:      "last",
+		Conns:       1,
+		Consumers:   k.Consumers,
+		Processors:  k.Consumers,
+		MinBytes:    10240,
+		MaxBytes:    10485760,
+	}, kq.WithHandle(c.consume))
+	if err != nil {
+		return nil, err
+	}
+	c.queue = q
+	return c, nil
+}
+
+// Start 在 Stop 之后调用时直接返回
+func (c *KafkaConsumer) Start(handler Handler) {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.handler = handler
+	c.started = true
+	c.mu.Unlock()
+	c.queue.Start()
+}
+
+func (c *KafkaConsumer) Stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+	c.stopped = true
+	close(c.done)
+	if c.started {
+		c.queue.Stop()
+	}
+}
+
+// consume 返回 nil 时 kq 才会提交位移，无法解析的事件重试也不会成功，记录日志后跳过
+func (c *KafkaConsumer) consume(_, value string) error {
+	var event Event
+	if err := json.Unmarshal([]byte(value), &event); err != nil {
+		log.Error("[MQ] unmarshal event failed, value=%s, err=%v", value, err)
+		return nil
+	}
+	backoff := c.backoff
+	for {
+		err := c.handler(context.Background(), &event)
+		if err == nil {
+			return nil
+		}
+		log.Error("[MQ] handle event %s failed, retry in %s, err=%v", event.Type, backoff, err)
+		select {
+		case <-c.done:
+			return err
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package mq
+
+import (
+	"context"
+	"sync"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+)
+
+var _ Consumer = (*MemoryConsumer)(nil)
+
+// MemoryConsumer 进程内的事件队列，用于本地开发和测试
+type MemoryConsumer struct {
+	events chan *Event
+	done   chan struct{}
+	once   sync.Once
+}
+
+func NewMemoryConsumer(buffer int) *MemoryConsumer {
+	return &MemoryConsumer{
+		events: make(chan *Event, buffer),
+		done:   make(chan struct{}),
+	}
+}
+
+// Publish 投递一个事件，队列已满时阻塞
+func (c *MemoryConsumer) Publish(event *Event) {
+	select {
+	case c.events <- event:
+	case <-c.done:
+	}
+}
+
+func (c *MemoryConsumer) Start(handler Handler) {
+	for {
+		select {
+		case event := <-c.events:
+			if err := handler(context.Background(), event); err != nil {
+				log.Error("[MQ] handle event %s failed, err=%v", event.Type, err)
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *MemoryConsumer) Stop() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
@@ -0,0 +1,105 @@
+package sensitive
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/CloudStriver/go-pkg/utils/util/log"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+)
+
+// defaultMask 未配置掩码字符时使用
+const defaultMask = '*'
+
+type IFilter interface {
+	// Contains 判断文本中是否含有敏感词
+	Contains(text string) bool
+	// Mask 将文本中的敏感词逐字替换为掩码字符
+	Mask(text string) string
+}
+
+// Filter 敏感词过滤器，词表来自配置与词表文件，文件变更后自动重新加载
+type Filter struct {
+	config  *config.Config
+	matcher atomic.Pointer[matcher]
+	modTime time.Time
+}
+
+func NewFilter(config *config.Config, lc *lifecycle.Lifecycle) (IFilter, error) {
+	f := &Filter{config: config}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	if config.Sensitive.WordFile != "" {
+		lc.Append(lifecycle.Ticker(context.Background(), "sensitive", config.Sensitive.ReloadInterval, func(ctx context.Context) {
+			if err := f.reload(); err != nil {
+				log.CtxError(ctx, "[Sensitive] reload word file failed, err=%v", err)
+			}
+		}))
+	}
+	return f, nil
+}
+
+func (f *Filter) Contains(text string) bool {
+	return len(f.matcher.Load().find([]rune(text))) > 0
+}
+
+func (f *Filter) Mask(text string) string {
+	runes := []rune(text)
+	matches := f.matcher.Load().find(runes)
+	if len(matches) == 0 {
+		return text
+	}
+	mask := defaultMask
+	if r := []rune(f.config.Sensitive.Mask); len(r) > 0 {
+		mask = r[0]
+	}
+	for _, m := range matches {
+		for i := m.start; i < m.end; i++ {
+			runes[i] = mask
+		}
+	}
+	return string(runes)
+}
+
+// reload 词表文件的修改时间变化时重建自动机
+func (f *Filter) reload() error {
+	words := f.config.Sensitive.Words
+	if path := f.config.Sensitive.WordFile; path != "" {
+		stat, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if f.matcher.Load() != nil && stat.ModTime().Equal(f.modTime) {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f.modTime = stat.ModTime()
+		words = append(readWords(content), words...)
+	}
+	f.matcher.Store(newMatcher(words))
+	return nil
+}
+
+// readWords 每行一个敏感词，忽略空行与 # 开头的注释
+func readWords(content []byte) []string {
+	var words []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		w := strings.TrimSpace(scanner.Text())
+		if w != "" && !strings.HasPrefix(w, "#") {
+			words = append(words, w)
+		}
+	}
+	return words
+}
@@ -0,0 +1,65 @@
+package sensitive
+
+import (
+	"testing"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+)
+
+func newTestFilter(t *testing.T, words []string, mask string) *Filter {
+	c := &config.Config{}
+	c.Sensitive.Words = words
+	c.Sensitive.Mask = mask
+	f := &Filter{config: c}
+	if err := f.reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	return f
+}
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name         string
+		words        []string
+		mask         string
+		text         string
+		wantContains bool
+		wantMask     string
+	}{
+		{name: "no words", text: "hello", wantMask: "hello"},
+		{name: "no match", words: []string{"spam"}, mask: "*", text: "hello", wantMask: "hello"},
+		{name: "ascii", words: []string{"spam"}, mask: "*", text: "buy spam now", wantContains: true, wantMask: "buy **** now"},
+		{name: "case insensitive text", words: []string{"spam"}, mask: "*", text: "buy SpAm now", wantContains: true, wantMask: "buy **** now"},
+		{name: "case insensitive word", words: []string{"SPAM"}, mask: "*", text: "spam", wantContains: true, wantMask: "****"},
+		{name: "chinese", words: []string{"广告"}, mask: "*", text: "这是广告吗", wantContains: true, wantMask: "这是**吗"},
+		{name: "overlapping", words: []string{"abc", "bcd"}, mask: "*", text: "xabcdx", wantContains: true, wantMask: "x****x"},
+		{name: "nested", words: []string{"he", "she", "hers"}, mask: "*", text: "ushers", wantContains: true, wantMask: "u*****"},
+		{name: "multi-rune mask uses first rune", words: []string{"spam"}, mask: "#!", text: "spam", wantContains: true, wantMask: "####"},
+		{name: "empty mask falls back", words: []string{"spam"}, mask: "", text: "spam!", wantContains: true, wantMask: "****!"},
+		{name: "empty word ignored", words: []string{""}, mask: "*", text: "hello", wantMask: "hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestFilter(t, tt.words, tt.mask)
+			if got := f.Contains(tt.text); got != tt.wantContains {
+				t.Errorf("Contains(%q) = %v, want %v", tt.text, got, tt.wantContains)
+			}
+			if got := f.Mask(tt.text); got != tt.wantMask {
+				t.Errorf("Mask(%q) = %q, want %q", tt.text, got, tt.wantMask)
+			}
+		})
+	}
+}
+
+func TestReadWords(t *testing.T) {
+	got := readWords([]byte("# comment\n\nspam\n  广告  \n"))
+	want := []string{"spam", "广告"}
+	if len(got) != len(want) {
+		t.Fatalf("readWords = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("readWords[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package sensitive
+
+import "unicode"
+
+type (
+	// matcher 基于 Aho–Corasick 自动机的多模式匹配，按 rune 处理以支持中文，匹配时不区分大小写
+	matcher struct {
+		nodes []*node
+	}
+	node struct {
+		next map[rune]int
+		fail int
+		// depth 以该节点结尾的最长敏感词长度，0 表示不是词尾
+		depth int
+	}
+	// match 命中的敏感词在文本中的 rune 区间 [start, end)
+	match struct {
+		start int
+		end   int
+	}
+)
+
+func newMatcher(words []string) *matcher {
+	m := &matcher{nodes: []*node{{next: map[rune]int{}}}}
+	for _, w := range words {
+		m.insert([]rune(w))
+	}
+	m.build()
+	return m
+}
+
+func (m *matcher) insert(word []rune) {
+	if len(word) == 0 {
+		return
+	}
+	cur := 0
+	for _, r := range word {
+		r = unicode.ToLower(r)
+		nxt, ok := m.nodes[cur].next[r]
+		if !ok {
+			m.nodes = append(m.nodes, &node{next: map[rune]int{}})
+			nxt = len(m.nodes) - 1
+			m.nodes[cur].next[r] = nxt
+		}
+		cur = nxt
+	}
+	if len(word) > m.nodes[cur].depth {
+		m.nodes[cur].depth = len(word)
+	}
+}
+
+// build 按层序构造失配指针，并把后缀节点的词尾信息合并到当前节点
+func (m *matcher) build() {
+	queue := make([]int, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for r, child := range m.nodes[cur].next {
+			f := m.nodes[cur].fail
+			for f != 0 && !m.hasNext(f, r) {
+				f = m.nodes[f].fail
+			}
+			if nxt, ok := m.nodes[f].next[r]; ok && nxt != child {
+				m.nodes[child].fail = nxt
+			}
+			if d := m.nodes[m.nodes[child].fail].depth; d > m.nodes[child].depth {
+				m.nodes[child].depth = d
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+func (m *matcher) hasNext(n int, r rune) bool {
+	_, ok := m.nodes[n].next[r]
+	return ok
+}
+
+func (m *matcher) find(text []rune) []match {
+	var res []match
+	cur := 0
+	for i, r := range text {
+		r = unicode.ToLower(r)
+		for cur != 0 && !m.hasNext(cur, r) {
+			cur = m.nodes[cur].fail
+		}
+		if nxt, ok := m.nodes[cur].next[r]; ok {
+			cur = nxt
+		}
+		if d := m.nodes[cur].depth; d > 0 {
+			res = append(res, match{start: i + 1 - d, end: i + 1})
+		}
+	}
+	return res
+}
@@ -0,0 +1,20 @@
+package mongo
+
+import (
+	"github.com/zeromicro/go-zero/core/stores/mon"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+)
+
+// NewMongo 返回业务库，用于健康检查等不属于某个集合的操作，go-zero 按 URL 复用客户端，不会新建连接；
+// 所有 mapper 共用这个客户端，因此退出时由这里统一断开
+func NewMongo(config *config.Config, lc *lifecycle.Lifecycle) *mongo.Database {
+	db := mon.MustNewModel(config.Mongo.URL, config.Mongo.DB, "").Database()
+	lc.AppendStore(lifecycle.Hook{
+		Name:   "mongo",
+		OnStop: db.Client().Disconnect,
+	})
+	return db
+}
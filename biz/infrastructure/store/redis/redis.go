@@ -1,10 +1,13 @@
 package redis
 
 import (
-	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
 	"github.com/zeromicro/go-zero/core/stores/redis"
+
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
 )
 
+// NewRedis 返回业务使用的 Redis。底层连接由 go-zero 按地址缓存并与 mapper 的缓存共用，go-zero 没有提供关闭的方法，
+// 因此不注册关闭回调，连接随进程退出释放
 func NewRedis(config *config.Config) *redis.Redis {
 	return redis.MustNewRedis(config.RedisConf)
 }
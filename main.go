@@ -1,8 +1,13 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"net"
+	// 镜像中只带有 Asia/Shanghai 时区数据，用户时区需要内嵌的完整时区库
+	_ "time/tzdata"
 
+	adaptormiddleware "github.com/CloudStriver/cloudmind-system/biz/adaptor/middleware"
 	"github.com/CloudStriver/cloudmind-system/provider"
 	"github.com/CloudStriver/go-pkg/utils/kitex/middleware"
 	"github.com/CloudStriver/go-pkg/utils/util/log"
@@ -13,12 +18,24 @@ import (
 	"github.com/kitex-contrib/obs-opentelemetry/tracing"
 )
 
+// backfill 为收到过通知但缺少已读记录的用户补建记录，完成后直接退出
+var backfill = flag.Bool("backfill-notification-count", false, "create missing notificationCount rows and exit")
+
 func main() {
+	flag.Parse()
 	klog.SetLogger(log.NewKlogLogger())
 	s, err := provider.NewSystemServerImpl()
 	if err != nil {
 		panic(err)
 	}
+	if *backfill {
+		n, err := s.SystemService.BackfillNotificationCounts(context.Background())
+		if err != nil {
+			panic(err)
+		}
+		log.Info("backfilled %d notificationCount rows", n)
+		return
+	}
 	addr, err := net.ResolveTCPAddr("tcp", s.ListenOn)
 	if err != nil {
 		panic(err)
@@ -28,11 +45,26 @@ func main() {
 		server.WithServiceAddr(addr),
 		server.WithSuite(tracing.NewServerSuite()),
 		server.WithServerBasicInfo(&rpcinfo.EndpointBasicInfo{ServiceName: s.Name}),
+		// 收到退出信号后停止接收新请求，并最多等待 RPCTimeout 让进行中的请求完成
+		server.WithExitSignal(s.Lifecycle.ExitSignal),
+		server.WithExitWaitTime(s.Shutdown.RPCTimeout),
 		server.WithMiddleware(middleware.LogMiddleware(s.Name)),
+		server.WithMiddleware(adaptormiddleware.AuthMiddleware(s.Config)),
+		server.WithMiddleware(adaptormiddleware.OverloadMiddleware(s.Config, s.RPCLimiter)),
+		server.WithMiddleware(adaptormiddleware.AuditMiddleware(s.AuditService)),
 	)
+	if err = s.Lifecycle.Start(context.Background()); err != nil {
+		panic(err)
+	}
 	err = svr.Run()
 
 	if err != nil {
 		log.Error(err.Error())
 	}
+	// svr.Run 在 RPC 排空后返回，随后停止后台任务并关闭存储
+	ctx, cancel := context.WithTimeout(context.Background(), s.Shutdown.Timeout)
+	defer cancel()
+	if err = s.Lifecycle.Stop(ctx); err != nil {
+		log.Error("shutdown failed, err=%v", err)
+	}
 }
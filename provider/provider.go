@@ -1,8 +1,26 @@
 package provider
 
 import (
+	"github.com/CloudStriver/cloudmind-system/biz/adaptor/admin"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/channel"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/limiter"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mail"
+	auditmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/audit"
+	blockmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/block"
+	deliverymapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/delivery"
+	notificationackmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationAck"
 	notificationcountmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationCount"
+	notificationeventmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationEvent"
+	notificationstatmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationStat"
+	preferencemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
 	slidermapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/slider"
+	tombstonemapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/tombstone"
+	webhookmapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/webhook"
+	webhookdeliverymapper "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/webhookDelivery"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mq"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/sensitive"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/store/mongo"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/store/redis"
 	"github.com/google/wire"
 
@@ -12,22 +30,54 @@ import (
 )
 
 var AllProvider = wire.NewSet(
+	AdaptorSet,
 	ApplicationSet,
 	InfrastructureSet,
 )
 
+var AdaptorSet = wire.NewSet(
+	admin.NewServer,
+)
+
 var ApplicationSet = wire.NewSet(
 	service.SystemSet,
+	service.DeliverySet,
+	service.DigestSet,
+	service.EventSet,
+	service.WebhookSet,
+	service.RetentionSet,
+	service.SnoozeSet,
+	service.AnalyticsSet,
+	service.AdminSet,
+	service.AuditSet,
 )
 
 var InfrastructureSet = wire.NewSet(
 	config.NewConfig,
+	lifecycle.NewLifecycle,
 	redis.NewRedis,
+	mongo.NewMongo,
+	channel.NewChannels,
+	mail.NewSender,
+	limiter.NewNotificationLimiter,
+	limiter.NewRPCLimiter,
+	sensitive.NewFilter,
+	mq.NewConsumer,
 	MapperSet,
 )
 
 var MapperSet = wire.NewSet(
 	notificationmapper.NewNotificationModel,
 	notificationcountmapper.NewNotificationCountModel,
+	notificationackmapper.NewNotificationAckModel,
 	slidermapper.NewSliderModel,
+	deliverymapper.NewDeliveryModel,
+	preferencemapper.NewPreferenceModel,
+	webhookmapper.NewWebhookModel,
+	webhookdeliverymapper.NewWebhookDeliveryModel,
+	tombstonemapper.NewTombstoneModel,
+	blockmapper.NewBlockModel,
+	notificationeventmapper.NewNotificationEventModel,
+	notificationstatmapper.NewNotificationStatModel,
+	auditmapper.NewAuditModel,
 )
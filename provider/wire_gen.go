@@ -8,11 +8,29 @@ package provider
 
 import (
 	"github.com/CloudStriver/cloudmind-system/biz/adaptor"
+	"github.com/CloudStriver/cloudmind-system/biz/adaptor/admin"
 	"github.com/CloudStriver/cloudmind-system/biz/application/service"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/channel"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/config"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/lifecycle"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/limiter"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mail"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/audit"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/block"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/delivery"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notification"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationAck"
 	notification2 "github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationCount"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationEvent"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/notificationStat"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/preference"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/slider"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/tombstone"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/webhook"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mapper/webhookDelivery"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/mq"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/sensitive"
+	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/store/mongo"
 	"github.com/CloudStriver/cloudmind-system/biz/infrastructure/store/redis"
 )
 
@@ -23,19 +41,96 @@ func NewSystemServerImpl() (*adaptor.SystemServerImpl, error) {
 	if err != nil {
 		return nil, err
 	}
+	lifecycleLifecycle := lifecycle.NewLifecycle(configConfig)
 	iNotificationMongoMapper := notification.NewNotificationModel(configConfig)
 	iNotificationCountMongoMapper := notification2.NewNotificationCountModel(configConfig)
+	iNotificationAckMongoMapper := notificationAck.NewNotificationAckModel(configConfig)
 	iSliderMongoMapper := slider.NewSliderModel(configConfig)
+	iPreferenceMongoMapper := preference.NewPreferenceModel(configConfig)
 	redisRedis := redis.NewRedis(configConfig)
+	iDeliveryMongoMapper := delivery.NewDeliveryModel(configConfig)
+	sender := mail.NewSender(configConfig)
+	channels, err := channel.NewChannels(configConfig, sender, iPreferenceMongoMapper)
+	if err != nil {
+		return nil, err
+	}
+	deliveryService := service.NewDeliveryService(configConfig, lifecycleLifecycle, iDeliveryMongoMapper, channels, iPreferenceMongoMapper)
+	iNotificationLimiter := limiter.NewNotificationLimiter(configConfig, redisRedis)
+	iFilter, err := sensitive.NewFilter(configConfig, lifecycleLifecycle)
+	if err != nil {
+		return nil, err
+	}
+	iWebhookMongoMapper := webhook.NewWebhookModel(configConfig)
+	iWebhookDeliveryMongoMapper := webhookDelivery.NewWebhookDeliveryModel(configConfig)
+	iAuditMongoMapper := audit.NewAuditModel(configConfig)
+	auditServiceImpl := &service.AuditServiceImpl{
+		AuditMongoMapper:        iAuditMongoMapper,
+		SliderMongoMapper:       iSliderMongoMapper,
+		NotificationMongoMapper: iNotificationMongoMapper,
+	}
+	webhookService, err := service.NewWebhookService(configConfig, lifecycleLifecycle, iWebhookMongoMapper, iWebhookDeliveryMongoMapper, auditServiceImpl)
+	if err != nil {
+		return nil, err
+	}
+	iTombstoneMongoMapper := tombstone.NewTombstoneModel(configConfig)
+	iBlockMongoMapper := block.NewBlockModel(configConfig)
+	iNotificationEventMongoMapper := notificationEvent.NewNotificationEventModel(configConfig)
+	iNotificationStatMongoMapper := notificationStat.NewNotificationStatModel(configConfig)
+	analyticsService, err := service.NewAnalyticsService(configConfig, lifecycleLifecycle, iNotificationMongoMapper, iNotificationCountMongoMapper, iNotificationEventMongoMapper, iNotificationStatMongoMapper)
+	if err != nil {
+		return nil, err
+	}
 	systemServiceImpl := &service.SystemServiceImpl{
+		Config:                       configConfig,
 		NotificationMongoMapper:      iNotificationMongoMapper,
 		NotificationCountMongoMapper: iNotificationCountMongoMapper,
+		NotificationAckMongoMapper:   iNotificationAckMongoMapper,
 		SliderMongoMapper:            iSliderMongoMapper,
+		PreferenceMongoMapper:        iPreferenceMongoMapper,
 		Redis:                        redisRedis,
+		DeliveryService:              deliveryService,
+		NotificationLimiter:          iNotificationLimiter,
+		SensitiveFilter:              iFilter,
+		WebhookService:               webhookService,
+		TombstoneMongoMapper:         iTombstoneMongoMapper,
+		BlockMongoMapper:             iBlockMongoMapper,
+		AnalyticsService:             analyticsService,
+		AuditService:                 auditServiceImpl,
+	}
+	digestService := service.NewDigestService(configConfig, lifecycleLifecycle, iNotificationMongoMapper, iNotificationCountMongoMapper, iPreferenceMongoMapper, sender)
+	consumer, err := mq.NewConsumer(configConfig)
+	if err != nil {
+		return nil, err
+	}
+	eventService, err := service.NewEventService(configConfig, lifecycleLifecycle, systemServiceImpl, auditServiceImpl, consumer)
+	if err != nil {
+		return nil, err
+	}
+	retentionService := service.NewRetentionService(configConfig, lifecycleLifecycle, iNotificationMongoMapper, iTombstoneMongoMapper)
+	snoozeService := service.NewSnoozeService(configConfig, lifecycleLifecycle, iNotificationMongoMapper, deliveryService)
+	adminServiceImpl := &service.AdminServiceImpl{
+		NotificationMongoMapper: iNotificationMongoMapper,
+		TombstoneMongoMapper:    iTombstoneMongoMapper,
+		WebhookService:          webhookService,
+		AuditService:            auditServiceImpl,
 	}
+	irpcLimiter := limiter.NewRPCLimiter(configConfig, redisRedis)
+	database := mongo.NewMongo(configConfig, lifecycleLifecycle)
+	server := admin.NewServer(configConfig, lifecycleLifecycle, database, redisRedis)
 	systemServerImpl := &adaptor.SystemServerImpl{
-		Config:        configConfig,
-		SystemService: systemServiceImpl,
+		Config:           configConfig,
+		SystemService:    systemServiceImpl,
+		DigestService:    digestService,
+		EventService:     eventService,
+		WebhookService:   webhookService,
+		RetentionService: retentionService,
+		SnoozeService:    snoozeService,
+		AnalyticsService: analyticsService,
+		AdminService:     adminServiceImpl,
+		AuditService:     auditServiceImpl,
+		RPCLimiter:       irpcLimiter,
+		AdminServer:      server,
+		Lifecycle:        lifecycleLifecycle,
 	}
 	return systemServerImpl, nil
 }